@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordingResponseWriter wraps an http.ResponseWriter to capture the status
+// code and byte count a handler sent, so logging middleware can report on
+// handlers it otherwise knows nothing about.
+type RecordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *RecordingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *RecordingResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
+
+type accessLogFieldsKeyType struct{}
+
+var accessLogFieldsKey = accessLogFieldsKeyType{}
+
+// accessLogFields holds the handler-supplied %{name}x custom fields for one
+// request. A mutex isn't strictly needed under cgi.Serve (one request per
+// process), but costs nothing and keeps SetAccessLogField safe to call from
+// anywhere.
+type accessLogFields struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (f *accessLogFields) set(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.values == nil {
+		f.values = make(map[string]string)
+	}
+	f.values[key] = value
+}
+
+func (f *accessLogFields) snapshot() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]string, len(f.values))
+	for k, v := range f.values {
+		out[k] = v
+	}
+	return out
+}
+
+// SetAccessLogField records a %{key}x custom field on the current request's
+// access log entry. It's a no-op if the request didn't come through
+// WithAccessLog.
+func SetAccessLogField(r *http.Request, key, value string) {
+	if fields, ok := r.Context().Value(accessLogFieldsKey).(*accessLogFields); ok {
+		fields.set(key, value)
+	}
+}
+
+// accessLogEntry is one logged request, in a form that both formatCombined
+// and formatJSON can render.
+type accessLogEntry struct {
+	Host       string
+	RemoteUser string
+	Time       time.Time
+	Request    string
+	Status     int
+	Bytes      int
+	DurationUs int64
+	Referer    string
+	UserAgent  string
+	Custom     map[string]string
+}
+
+// WithAccessLog wraps next in a logging middleware that writes one record
+// per request in config.AccessLogFormat, modelled on Apache's combined log
+// format:
+//
+//	%h %l %u %t "%r" %>s %b %D "%{Referer}i" "%{User-agent}i"
+//
+// Handlers can add their own %{name}x fields via SetAccessLogField; the
+// request's authoritative timing (%D) is measured here, not by the handler.
+func WithAccessLog(config Config, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		fields := &accessLogFields{}
+		r = r.WithContext(context.WithValue(r.Context(), accessLogFieldsKey, fields))
+
+		rec := &RecordingResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		entry := accessLogEntry{
+			Host:       remoteHost(r),
+			RemoteUser: r.Header.Get("X-Remote-User"),
+			Time:       start,
+			Request:    fmt.Sprintf("%s %s %s", r.Method, r.URL.RequestURI(), r.Proto),
+			Status:     status,
+			Bytes:      rec.bytes,
+			DurationUs: time.Since(start).Microseconds(),
+			Referer:    r.Header.Get("Referer"),
+			UserAgent:  r.Header.Get("User-Agent"),
+			Custom:     fields.snapshot(),
+		}
+		if entry.RemoteUser == "" {
+			entry.RemoteUser = os.Getenv("REMOTE_USER")
+		}
+
+		if err := writeAccessLogEntry(config, entry); err != nil {
+			// Logging must never take the request down with it.
+			fmt.Fprintf(os.Stderr, "access log: %v\n", err)
+		}
+	})
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeAccessLogEntry(config Config, entry accessLogEntry) error {
+	writer, err := getAccessLogWriter(config)
+	if err != nil {
+		return err
+	}
+
+	var line string
+	if config.AccessLogFormat == "json" {
+		line = formatJSON(entry)
+	} else {
+		line = formatCombined(entry)
+	}
+
+	_, err = writer.Write([]byte(line + "\n"))
+	return err
+}
+
+func formatCombined(e accessLogEntry) string {
+	user := e.RemoteUser
+	if user == "" {
+		user = "-"
+	}
+	bytesField := "-"
+	if e.Bytes > 0 {
+		bytesField = strconv.Itoa(e.Bytes)
+	}
+
+	line := fmt.Sprintf(`%s - %s %s "%s" %d %s %d "%s" "%s"`,
+		e.Host,
+		user,
+		e.Time.Format("[02/Jan/2006:15:04:05 -0700]"),
+		e.Request,
+		e.Status,
+		bytesField,
+		e.DurationUs,
+		e.Referer,
+		e.UserAgent,
+	)
+
+	if len(e.Custom) == 0 {
+		return line
+	}
+
+	keys := make([]string, 0, len(e.Custom))
+	for k := range e.Custom {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var extra strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&extra, ` "%%{%s}x=%s"`, k, e.Custom[k])
+	}
+
+	return line + extra.String()
+}
+
+func formatJSON(e accessLogEntry) string {
+	record := map[string]interface{}{
+		"host":        e.Host,
+		"remote_user": e.RemoteUser,
+		"time":        e.Time.Format(time.RFC3339),
+		"request":     e.Request,
+		"status":      e.Status,
+		"bytes":       e.Bytes,
+		"duration_us": e.DurationUs,
+		"referer":     e.Referer,
+		"user_agent":  e.UserAgent,
+	}
+	for k, v := range e.Custom {
+		record[k] = v
+	}
+
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Sprintf(`{"error": %q}`, err.Error())
+	}
+	return string(body)
+}
+
+// rotatingFileWriter appends to a log file, rotating it once a day or once
+// it would exceed maxBytes (whichever comes first; maxBytes <= 0 disables
+// the size check). Each CGI request runs in its own fresh process (see
+// ensureStatusCacheTable in statuscache.go), so currentDate/currentSize can't be trusted
+// across writes the way an in-memory field normally would be - they're only
+// ever populated by openExisting stat-ing the file actually on disk, never
+// assumed from a previous call.
+type rotatingFileWriter struct {
+	mu          sync.Mutex
+	path        string
+	maxBytes    int64
+	file        *os.File
+	currentDate string
+	currentSize int64
+}
+
+func newRotatingFileWriter(path string) (*rotatingFileWriter, error) {
+	return &rotatingFileWriter{path: path}, nil
+}
+
+func (w *rotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.rotateIfNeeded(len(p)); err != nil {
+		return 0, err
+	}
+
+	n, err := w.file.Write(p)
+	w.currentSize += int64(n)
+	return n, err
+}
+
+// openExisting (re)opens w.path for appending, seeding currentDate/
+// currentSize from the file already on disk (if any) rather than from
+// in-memory state, since nothing guarantees the process that last wrote to
+// it is this one.
+func (w *rotatingFileWriter) openExisting() error {
+	date, size := "", int64(0)
+	if info, err := os.Stat(w.path); err == nil {
+		date = info.ModTime().Format("2006-01-02")
+		size = info.Size()
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to stat access log: %w", err)
+	}
+
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open access log: %w", err)
+	}
+
+	w.file = file
+	w.currentDate = date
+	w.currentSize = size
+	return nil
+}
+
+func (w *rotatingFileWriter) rotateIfNeeded(nextWriteLen int) error {
+	if w.file == nil {
+		if err := w.openExisting(); err != nil {
+			return err
+		}
+	}
+
+	today := time.Now().Format("2006-01-02")
+	needsRotate := (w.currentDate != "" && today != w.currentDate) ||
+		(w.maxBytes > 0 && w.currentSize+int64(nextWriteLen) > w.maxBytes)
+
+	if !needsRotate {
+		return nil
+	}
+
+	w.file.Close()
+	rotated := fmt.Sprintf("%s.%s-%d", w.path, w.currentDate, time.Now().UnixNano())
+	if err := os.Rename(w.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate access log: %w", err)
+	}
+
+	return w.openExisting()
+}
+
+var (
+	accessLogWriterOnce sync.Once
+	accessLogWriter     *rotatingFileWriter
+	accessLogWriterErr  error
+)
+
+// getAccessLogWriter lazily opens the rotating file writer for
+// config.AccessLogPath. One CGI process only ever logs to one path, so a
+// package-level singleton is enough.
+func getAccessLogWriter(config Config) (*rotatingFileWriter, error) {
+	accessLogWriterOnce.Do(func() {
+		accessLogWriter, accessLogWriterErr = newRotatingFileWriter(config.AccessLogPath)
+		if accessLogWriterErr == nil {
+			accessLogWriter.maxBytes = config.AccessLogMaxBytes
+		}
+	})
+	return accessLogWriter, accessLogWriterErr
+}
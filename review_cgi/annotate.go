@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// annotate.cgi records and resolves anchored annotations on a lemma's
+// Greek/English text fields (see annotations.go for the storage model).
+func main() {
+	contentLength := os.Getenv("CONTENT_LENGTH")
+	if contentLength == "" {
+		showAnnotateError("No POST data received")
+		return
+	}
+
+	length, err := strconv.Atoi(contentLength)
+	if err != nil || length <= 0 {
+		showAnnotateError("Invalid content length")
+		return
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(os.Stdin, body); err != nil {
+		showAnnotateError(fmt.Sprintf("Failed to read POST data: %v", err))
+		return
+	}
+
+	formData, err := url.ParseQuery(string(body))
+	if err != nil {
+		showAnnotateError(fmt.Sprintf("Failed to parse form data: %v", err))
+		return
+	}
+
+	remoteUser := os.Getenv("REMOTE_USER")
+	action := formData.Get("action") // "" (create) or "resolve"
+
+	lemmaID, err := strconv.Atoi(formData.Get("lemma_id"))
+	if err != nil {
+		showAnnotateError("Invalid lemma ID")
+		return
+	}
+
+	config := GetConfig()
+	ctx, cancel := RequestContext(config)
+	defer cancel()
+
+	db, err := OpenDatabase(ctx, config.DBPath)
+	if err != nil {
+		showAnnotateError(fmt.Sprintf("Failed to open database: %v", err))
+		return
+	}
+	defer db.Close()
+
+	if action == "resolve" {
+		annotationID, err := strconv.Atoi(formData.Get("annotation_id"))
+		if err != nil {
+			showAnnotateError("Invalid annotation ID")
+			return
+		}
+		if err := ResolveAnnotation(ctx, db, annotationID); err != nil {
+			showAnnotateError(fmt.Sprintf("Failed to resolve annotation: %v", err))
+			return
+		}
+		log.Printf("Annotation resolved: id=%d, user=%s", annotationID, remoteUser)
+		redirectToLemma(lemmaID)
+		return
+	}
+
+	field := formData.Get("field")
+	validFields := map[string]bool{
+		"greek_text":          true,
+		"english_translation": true,
+		"corrected_greek":     true,
+		"corrected_english":   true,
+	}
+	// "image:<filename>" anchors a note to a rectangular region of a source
+	// page scan, drawn in the IIIF deep-zoom viewer, rather than to a text
+	// range.
+	if !validFields[field] && !strings.HasPrefix(field, "image:") {
+		showAnnotateError("Invalid annotation field")
+		return
+	}
+
+	startOffset, err := strconv.Atoi(formData.Get("start_offset"))
+	if err != nil {
+		showAnnotateError("Invalid start offset")
+		return
+	}
+	endOffset, err := strconv.Atoi(formData.Get("end_offset"))
+	if err != nil || endOffset < startOffset {
+		showAnnotateError("Invalid end offset")
+		return
+	}
+
+	annotation := &Annotation{
+		LemmaID:     lemmaID,
+		Field:       field,
+		StartOffset: startOffset,
+		EndOffset:   endOffset,
+		QuotedText:  formData.Get("quoted_text"),
+		Author:      remoteUser,
+		Body:        strings.TrimSpace(formData.Get("body")),
+	}
+
+	if err := CreateAnnotation(ctx, db, annotation); err != nil {
+		showAnnotateError(fmt.Sprintf("Failed to create annotation: %v", err))
+		return
+	}
+
+	log.Printf("Annotation created: lemma_id=%d, field=%s, user=%s", lemmaID, field, remoteUser)
+	redirectToLemma(lemmaID)
+}
+
+func showAnnotateError(message string) {
+	fmt.Println("Content-Type: text/html; charset=utf-8")
+	fmt.Println()
+	fmt.Printf(`<!DOCTYPE html>
+<html>
+<head>
+    <title>Error - Annotate</title>
+</head>
+<body>
+    <h1>Error Adding Annotation</h1>
+    <p>%s</p>
+</body>
+</html>`, HTMLEscape(message))
+
+	log.Printf("Error: %s", message)
+}
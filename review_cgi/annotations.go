@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html/template"
+	"sort"
+	"time"
+)
+
+// Annotation is an anchored, thread-style comment on a character range
+// within one of a lemma's text fields. Offsets are Unicode code points, not
+// bytes -- critical for polytonic Greek, where a single precomposed or
+// decomposed character can be multiple UTF-8 bytes.
+type Annotation struct {
+	ID          int
+	LemmaID     int
+	Field       string // greek_text, english_translation, corrected_greek, corrected_english, or image:<filename> for a page-scan region
+	StartOffset int
+	EndOffset   int
+	QuotedText  string
+	Author      string
+	Body        string
+	Resolved    bool
+	CreatedAt   time.Time
+}
+
+// ensureAnnotationsTable creates annotations if it doesn't already exist.
+// Like review_events and review_revisions, this table is introduced by this
+// feature, so nothing else creates it.
+func ensureAnnotationsTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS annotations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lemma_id INTEGER NOT NULL,
+			field TEXT,
+			start_offset INTEGER,
+			end_offset INTEGER,
+			quoted_text TEXT,
+			author TEXT,
+			body TEXT,
+			resolved INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create annotations table: %w", err)
+	}
+	return nil
+}
+
+// CreateAnnotation inserts a new anchored annotation.
+func CreateAnnotation(ctx context.Context, db *sql.DB, a *Annotation) error {
+	if err := ensureAnnotationsTable(ctx, db); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO annotations (
+			lemma_id, field, start_offset, end_offset, quoted_text, author, body, resolved, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?)
+	`, a.LemmaID, a.Field, a.StartOffset, a.EndOffset, a.QuotedText, a.Author, a.Body, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to create annotation: %w", err)
+	}
+	return nil
+}
+
+// GetAnnotationsForLemma returns all annotations on a lemma, oldest first.
+func GetAnnotationsForLemma(ctx context.Context, db *sql.DB, lemmaID int) ([]Annotation, error) {
+	if err := ensureAnnotationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, lemma_id, field, start_offset, end_offset,
+		       COALESCE(quoted_text, ''), author, COALESCE(body, ''), resolved, created_at
+		FROM annotations
+		WHERE lemma_id = ?
+		ORDER BY created_at ASC
+	`, lemmaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query annotations: %w", err)
+	}
+	defer rows.Close()
+
+	var annotations []Annotation
+	for rows.Next() {
+		var a Annotation
+		if err := rows.Scan(&a.ID, &a.LemmaID, &a.Field, &a.StartOffset, &a.EndOffset,
+			&a.QuotedText, &a.Author, &a.Body, &a.Resolved, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %w", err)
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, nil
+}
+
+// ResolveAnnotation marks an annotation thread as resolved.
+func ResolveAnnotation(ctx context.Context, db *sql.DB, id int) error {
+	if err := ensureAnnotationsTable(ctx, db); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `UPDATE annotations SET resolved = 1 WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to resolve annotation: %w", err)
+	}
+	return nil
+}
+
+// CountOpenAnnotations returns the number of unresolved annotations on a lemma.
+func CountOpenAnnotations(ctx context.Context, db *sql.DB, lemmaID int) (int, error) {
+	if err := ensureAnnotationsTable(ctx, db); err != nil {
+		return 0, err
+	}
+	var count int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM annotations WHERE lemma_id = ? AND resolved = 0`, lemmaID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count open annotations: %w", err)
+	}
+	return count, nil
+}
+
+// GetNextWithOpenAnnotations finds the next lemma, in sort order after
+// currentLemma, that has at least one unresolved annotation.
+func GetNextWithOpenAnnotations(ctx context.Context, db *sql.DB, data *LemmaData, currentLemma *Lemma) (*Lemma, error) {
+	if err := ensureAnnotationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT lemma_id FROM annotations WHERE resolved = 0`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open annotations: %w", err)
+	}
+	defer rows.Close()
+
+	openIDs := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			continue
+		}
+		openIDs[id] = true
+	}
+
+	for i := currentLemma.SortOrder + 1; i < len(data.Lemmas); i++ {
+		if openIDs[data.Lemmas[i].ID] {
+			return &data.Lemmas[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// annotateHTML wraps the annotated code-point ranges of text (for the given
+// field) in <mark data-ann-id="..."> spans, HTML-escaping everything else.
+// It is registered as a template func so reviewTemplate can render marks
+// directly over the original Greek/English text.
+func annotateHTML(text string, field string, annotations []Annotation) template.HTML {
+	var relevant []Annotation
+	for _, a := range annotations {
+		if a.Field == field {
+			relevant = append(relevant, a)
+		}
+	}
+	if len(relevant) == 0 {
+		return template.HTML(HTMLEscape(text))
+	}
+	sort.Slice(relevant, func(i, j int) bool { return relevant[i].StartOffset < relevant[j].StartOffset })
+
+	runes := []rune(text)
+	var out string
+	pos := 0
+	for _, a := range relevant {
+		start, end := a.StartOffset, a.EndOffset
+		if start < pos || start > len(runes) || end > len(runes) || end < start {
+			continue
+		}
+		class := "ann-mark"
+		if a.Resolved {
+			class = "ann-mark ann-resolved"
+		}
+		out += HTMLEscape(string(runes[pos:start]))
+		out += fmt.Sprintf(`<mark data-ann-id="%d" class="%s">`, a.ID, class)
+		out += HTMLEscape(string(runes[start:end]))
+		out += `</mark>`
+		pos = end
+	}
+	out += HTMLEscape(string(runes[pos:]))
+	return template.HTML(out)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ReviewEvent is a single timeline entry in a lemma's multi-reviewer
+// approval workflow: a sign-off, a request for changes, a comment, or an
+// unsubmitted draft correction.
+type ReviewEvent struct {
+	ID        int
+	LemmaID   int
+	Reviewer  string
+	EventType string // pending, approved, request_changes, commented
+	Field     string // greek, english, overall
+	Content   string
+	CreatedAt time.Time
+}
+
+// ensureReviewEventsTable creates review_events if it doesn't already
+// exist. Like review_revisions, this table is introduced by this feature,
+// so nothing else creates it.
+func ensureReviewEventsTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS review_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lemma_id INTEGER NOT NULL,
+			reviewer TEXT,
+			event_type TEXT,
+			field TEXT,
+			content TEXT,
+			created_at TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create review_events table: %w", err)
+	}
+	return nil
+}
+
+// RecordReviewEvent appends an event to a lemma's review timeline.
+func RecordReviewEvent(ctx context.Context, db *sql.DB, lemmaID int, reviewer, eventType, field, content string) error {
+	if err := ensureReviewEventsTable(ctx, db); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO review_events (lemma_id, reviewer, event_type, field, content, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, lemmaID, reviewer, eventType, field, content, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record review event: %w", err)
+	}
+	return nil
+}
+
+// ClearPendingDraft removes a reviewer's outstanding pending draft for a
+// field, called once their edits are folded into a real submission.
+func ClearPendingDraft(ctx context.Context, db *sql.DB, lemmaID int, reviewer, field string) error {
+	if err := ensureReviewEventsTable(ctx, db); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `
+		DELETE FROM review_events
+		WHERE lemma_id = ? AND reviewer = ? AND field = ? AND event_type = 'pending'
+	`, lemmaID, reviewer, field)
+	if err != nil {
+		return fmt.Errorf("failed to clear pending draft: %w", err)
+	}
+	return nil
+}
+
+// GetReviewEvents returns a lemma's timeline, oldest first. Other
+// reviewers' unsubmitted drafts (event_type "pending") are filtered out
+// unless they belong to viewer.
+func GetReviewEvents(ctx context.Context, db *sql.DB, lemmaID int, viewer string) ([]ReviewEvent, error) {
+	if err := ensureReviewEventsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, lemma_id, reviewer, event_type, field, COALESCE(content, ''), created_at
+		FROM review_events
+		WHERE lemma_id = ?
+		ORDER BY created_at ASC
+	`, lemmaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []ReviewEvent
+	for rows.Next() {
+		var e ReviewEvent
+		if err := rows.Scan(&e.ID, &e.LemmaID, &e.Reviewer, &e.EventType, &e.Field, &e.Content, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan review event: %w", err)
+		}
+		if e.EventType == "pending" && e.Reviewer != viewer {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// ApprovalStatus computes the effective approval state for a lemma: it is
+// "approved" once at least requiredApprovals distinct reviewers have an
+// "approved" event on the "overall" field with no "request_changes" from
+// that same reviewer recorded after their approval. Otherwise "pending".
+func ApprovalStatus(ctx context.Context, db *sql.DB, lemmaID int, requiredApprovals int) (string, error) {
+	if err := ensureReviewEventsTable(ctx, db); err != nil {
+		return "", err
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT reviewer, event_type, created_at
+		FROM review_events
+		WHERE lemma_id = ? AND field = 'overall' AND event_type IN ('approved', 'request_changes')
+		ORDER BY created_at ASC
+	`, lemmaID)
+	if err != nil {
+		return "", fmt.Errorf("failed to query approval events: %w", err)
+	}
+	defer rows.Close()
+
+	type reviewerState struct {
+		approved       bool
+		approvedAt     time.Time
+		requestChanges bool
+	}
+	byReviewer := make(map[string]*reviewerState)
+
+	for rows.Next() {
+		var reviewer, eventType string
+		var createdAt time.Time
+		if err := rows.Scan(&reviewer, &eventType, &createdAt); err != nil {
+			return "", fmt.Errorf("failed to scan approval event: %w", err)
+		}
+		s, ok := byReviewer[reviewer]
+		if !ok {
+			s = &reviewerState{}
+			byReviewer[reviewer] = s
+		}
+		switch eventType {
+		case "approved":
+			s.approved = true
+			s.approvedAt = createdAt
+			s.requestChanges = false
+		case "request_changes":
+			if !s.approved || createdAt.After(s.approvedAt) {
+				s.requestChanges = true
+			}
+		}
+	}
+
+	approvals := 0
+	for _, s := range byReviewer {
+		if s.approved && !s.requestChanges {
+			approvals++
+		}
+	}
+
+	if approvals >= requiredApprovals {
+		return "approved", nil
+	}
+	return "pending", nil
+}
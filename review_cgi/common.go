@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -14,57 +15,119 @@ import (
 
 // Lemma represents a single lemma entry from the JSON export
 type Lemma struct {
-	ID                    int      `json:"id"`
-	Lemma                 string   `json:"lemma"`
-	EntryNumber           int      `json:"entry_number"`
-	Version               string   `json:"version"`
-	GreekText             string   `json:"greek_text"`
-	EnglishTranslation    string   `json:"english_translation"`
-	Type                  string   `json:"type"`
-	VolumeLabel           string   `json:"volume_label"`
-	MeinekeID             string   `json:"meineke_id"`
-	BillerbeckID          string   `json:"billerbeck_id"`
-	WordCount             int      `json:"word_count"`
-	ImageFilenames        []string `json:"image_filenames"`
-	Confidence            string   `json:"confidence"`
-	Letter                string   `json:"letter"`
-	SortOrder             int      `json:"sort_order"`
+	ID                 int      `json:"id"`
+	Lemma              string   `json:"lemma"`
+	EntryNumber        int      `json:"entry_number"`
+	Version            string   `json:"version"`
+	GreekText          string   `json:"greek_text"`
+	EnglishTranslation string   `json:"english_translation"`
+	Type               string   `json:"type"`
+	VolumeLabel        string   `json:"volume_label"`
+	MeinekeID          string   `json:"meineke_id"`
+	BillerbeckID       string   `json:"billerbeck_id"`
+	WordCount          int      `json:"word_count"`
+	ImageFilenames     []string `json:"image_filenames"`
+	Confidence         string   `json:"confidence"`
+	Letter             string   `json:"letter"`
+	SortOrder          int      `json:"sort_order"`
 }
 
 // LemmaData contains all lemmas from JSON export
 type LemmaData struct {
-	Lemmas      []Lemma   `json:"lemmas"`
-	TotalCount  int       `json:"total_count"`
-	ExportedAt  time.Time `json:"exported_at"`
+	Lemmas     []Lemma   `json:"lemmas"`
+	TotalCount int       `json:"total_count"`
+	ExportedAt time.Time `json:"exported_at"`
 }
 
 // Review represents review data from SQLite
 type Review struct {
-	LemmaID                       int
-	ReviewStatus                  string
-	CorrectedGreekText            string
-	CorrectedEnglishTranslation   string
-	ReviewerUsername              string
-	ReviewedAt                    *time.Time
-	Notes                         string
+	LemmaID                     int
+	ReviewStatus                string
+	CorrectedGreekText          string
+	CorrectedEnglishTranslation string
+	ReviewedEnglishTranslation  string
+	ReviewerUsername            string
+	ReviewedAt                  *time.Time
+	Notes                       string
+	GreekCorrectedBy            string
+	InitialTranslationBy        string
+	ReviewedTranslationBy       string
+
+	// Spaced-repetition scheduling state (SM-2), keyed on the lemma.
+	EaseFactor   float64
+	IntervalDays int
+	Repetitions  int
+	DueAt        *time.Time
+
+	// Letter is the lemma's letter, set by callers that already have it
+	// loaded (it isn't a reviews column). SaveReview uses it only to know
+	// which status.cgi cache entry to invalidate.
+	Letter string
 }
 
 // Config holds application configuration
 type Config struct {
-	DataFile    string
-	DBPath      string
+	DataFile     string
+	DBPath       string
 	ProtectedURL string
+
+	// RequiredApprovals is how many distinct reviewers must approve a
+	// lemma's overall review before it is considered approved.
+	RequiredApprovals int
+
+	// ImagesDir is where the original source page scans live on disk, and
+	// TileCacheDir is where iiif.cgi caches tiles it generates from them.
+	ImagesDir    string
+	TileCacheDir string
+
+	// StatusCacheTTL is how long status.cgi's cache (the status_cache table,
+	// see statuscache.go) serves a letter's response before recomputing it.
+	StatusCacheTTL time.Duration
+
+	// AccessLogPath is where the WithAccessLog middleware (see accesslog.go)
+	// writes one record per request. AccessLogFormat is "combined" (Apache
+	// mod_log_config style) or "json". AccessLogMaxBytes rotates the file
+	// once it would exceed this size (0 disables size-based rotation); it
+	// also always rotates daily.
+	AccessLogPath    string
+	AccessLogFormat  string
+	AccessLogMaxBytes int64
+
+	// QueryTimeout bounds how long one request's DB calls (GetReview,
+	// SaveReview, GetReviewStats, the status.cgi query loop, ...) may run in
+	// total before their shared context is canceled, so a stuck SQLite lock
+	// or a disconnected client
+	// can't pin a CGI worker indefinitely.
+	QueryTimeout time.Duration
 }
 
 // GetConfig returns the application configuration
 func GetConfig() Config {
 	return Config{
-		DataFile:     "../db/review_data.json",
-		DBPath:       "../db/reviews.db",
-		ProtectedURL: "/protected/",
+		DataFile:          "../db/review_data.json",
+		DBPath:            "../db/reviews.db",
+		ProtectedURL:      "/protected/",
+		RequiredApprovals: 2,
+		ImagesDir:         "../protected",
+		TileCacheDir:      "../cache/iiif",
+		StatusCacheTTL:    60 * time.Second,
+		AccessLogPath:     "../logs/access.log",
+		AccessLogFormat:   "combined",
+		AccessLogMaxBytes: 100 * 1024 * 1024,
+		QueryTimeout:      5 * time.Second,
 	}
 }
 
+// RequestContext derives a context bounded by config.QueryTimeout for
+// handlers that don't already have one from an inbound http.Request (plain
+// CGI entrypoints parse CONTENT_LENGTH/QUERY_STRING themselves rather than
+// going through net/http). Callers with a request context should derive
+// their own via context.WithTimeout(r.Context(), config.QueryTimeout)
+// instead, so cancellation also follows the client disconnecting.
+func RequestContext(config Config) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), config.QueryTimeout)
+}
+
 // LoadLemmaData loads all lemmas from JSON file
 func LoadLemmaData(filepath string) (*LemmaData, error) {
 	file, err := os.Open(filepath)
@@ -88,42 +151,107 @@ func LoadLemmaData(filepath string) (*LemmaData, error) {
 }
 
 // OpenDatabase opens SQLite database connection
-func OpenDatabase(dbPath string) (*sql.DB, error) {
+func OpenDatabase(ctx context.Context, dbPath string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Test connection
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	// Let writers wait out a lock instead of immediately failing with
+	// SQLITE_BUSY when another CGI process is mid-transaction.
+	if _, err := db.ExecContext(ctx, `PRAGMA busy_timeout = 5000`); err != nil {
+		return nil, fmt.Errorf("failed to set busy_timeout: %w", err)
+	}
+
+	// Keep the "not_reviewed" scans (GetFirstUnreviewedInLetter, status.cgi)
+	// index-backed rather than full table scans.
+	if _, err := db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS reviews_status_idx ON reviews(review_status)`); err != nil {
+		return nil, fmt.Errorf("failed to create reviews_status_idx: %w", err)
+	}
+
+	if err := ensureReviewColumns(ctx, db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
+// ensureReviewColumns adds the columns GetReview/SaveReview depend on to the
+// pre-existing reviews table if they're missing: reviewed_english_translation
+// and the per-field "by" attribution columns, plus ef/interval_days/
+// repetitions/due_at for the SM-2 scheduler. reviews itself predates this
+// codebase, so this only ever adds columns, never creates the table.
+func ensureReviewColumns(ctx context.Context, db schemaExecer) error {
+	columns := []struct {
+		name, sqlType string
+	}{
+		{"reviewed_english_translation", "TEXT"},
+		{"greek_corrected_by", "TEXT"},
+		{"initial_translation_by", "TEXT"},
+		{"reviewed_translation_by", "TEXT"},
+		{"ef", "REAL"},
+		{"interval_days", "INTEGER"},
+		{"repetitions", "INTEGER"},
+		{"due_at", "TIMESTAMP"},
+	}
+	for _, column := range columns {
+		has, err := hasColumn(ctx, db, "reviews", column.name)
+		if err != nil {
+			return err
+		}
+		if has {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE reviews ADD COLUMN %s %s`, column.name, column.sqlType)); err != nil {
+			return fmt.Errorf("failed to add reviews.%s: %w", column.name, err)
+		}
+	}
+	return nil
+}
+
 // GetReview retrieves review data for a lemma
-func GetReview(db *sql.DB, lemmaID int) (*Review, error) {
+func GetReview(ctx context.Context, db *sql.DB, lemmaID int) (*Review, error) {
 	query := `
 		SELECT lemma_id, review_status,
 		       COALESCE(corrected_greek_text, ''),
 		       COALESCE(corrected_english_translation, ''),
+		       COALESCE(reviewed_english_translation, ''),
 		       COALESCE(reviewer_username, ''),
 		       reviewed_at,
-		       COALESCE(notes, '')
+		       COALESCE(notes, ''),
+		       COALESCE(greek_corrected_by, ''),
+		       COALESCE(initial_translation_by, ''),
+		       COALESCE(reviewed_translation_by, ''),
+		       COALESCE(ef, 2.5),
+		       COALESCE(interval_days, 0),
+		       COALESCE(repetitions, 0),
+		       due_at
 		FROM reviews
 		WHERE lemma_id = ?
 	`
 
 	review := &Review{}
-	err := db.QueryRow(query, lemmaID).Scan(
+	err := db.QueryRowContext(ctx, query, lemmaID).Scan(
 		&review.LemmaID,
 		&review.ReviewStatus,
 		&review.CorrectedGreekText,
 		&review.CorrectedEnglishTranslation,
+		&review.ReviewedEnglishTranslation,
 		&review.ReviewerUsername,
 		&review.ReviewedAt,
 		&review.Notes,
+		&review.GreekCorrectedBy,
+		&review.InitialTranslationBy,
+		&review.ReviewedTranslationBy,
+		&review.EaseFactor,
+		&review.IntervalDays,
+		&review.Repetitions,
+		&review.DueAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -131,6 +259,7 @@ func GetReview(db *sql.DB, lemmaID int) (*Review, error) {
 		return &Review{
 			LemmaID:      lemmaID,
 			ReviewStatus: "not_reviewed",
+			EaseFactor:   2.5,
 		}, nil
 	}
 
@@ -141,42 +270,88 @@ func GetReview(db *sql.DB, lemmaID int) (*Review, error) {
 	return review, nil
 }
 
-// SaveReview saves or updates review data
-func SaveReview(db *sql.DB, review *Review) error {
+// SaveReview saves or updates review data. oldReview is the row being
+// replaced (as returned by GetReview) and remoteUser is the reviewer making
+// this save; together they let us attribute each field to whoever actually
+// last touched it, rather than overwriting attribution on every save.
+func SaveReview(ctx context.Context, db *sql.DB, review *Review, oldReview *Review, remoteUser string) error {
+	if review.CorrectedGreekText != "" && review.CorrectedGreekText != oldReview.CorrectedGreekText {
+		review.GreekCorrectedBy = remoteUser
+	}
+	if review.CorrectedEnglishTranslation != "" && review.CorrectedEnglishTranslation != oldReview.CorrectedEnglishTranslation {
+		review.InitialTranslationBy = remoteUser
+	}
+	if review.ReviewedEnglishTranslation != "" && review.ReviewedEnglishTranslation != oldReview.ReviewedEnglishTranslation {
+		review.ReviewedTranslationBy = remoteUser
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin review save: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO reviews (
 			lemma_id, review_status, corrected_greek_text,
-			corrected_english_translation, reviewer_username,
-			reviewed_at, notes
-		) VALUES (?, ?, ?, ?, ?, ?, ?)
+			corrected_english_translation, reviewed_english_translation,
+			reviewer_username, reviewed_at, notes,
+			greek_corrected_by, initial_translation_by, reviewed_translation_by,
+			ef, interval_days, repetitions, due_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(lemma_id) DO UPDATE SET
 			review_status = excluded.review_status,
 			corrected_greek_text = excluded.corrected_greek_text,
 			corrected_english_translation = excluded.corrected_english_translation,
+			reviewed_english_translation = excluded.reviewed_english_translation,
 			reviewer_username = excluded.reviewer_username,
 			reviewed_at = excluded.reviewed_at,
-			notes = excluded.notes
+			notes = excluded.notes,
+			greek_corrected_by = excluded.greek_corrected_by,
+			initial_translation_by = excluded.initial_translation_by,
+			reviewed_translation_by = excluded.reviewed_translation_by,
+			ef = excluded.ef,
+			interval_days = excluded.interval_days,
+			repetitions = excluded.repetitions,
+			due_at = excluded.due_at
 	`
 
-	_, err := db.Exec(query,
+	_, err = tx.ExecContext(ctx, query,
 		review.LemmaID,
 		review.ReviewStatus,
 		review.CorrectedGreekText,
 		review.CorrectedEnglishTranslation,
+		review.ReviewedEnglishTranslation,
 		review.ReviewerUsername,
 		time.Now(),
 		review.Notes,
+		review.GreekCorrectedBy,
+		review.InitialTranslationBy,
+		review.ReviewedTranslationBy,
+		review.EaseFactor,
+		review.IntervalDays,
+		review.Repetitions,
+		review.DueAt,
 	)
-
 	if err != nil {
 		return fmt.Errorf("failed to save review: %w", err)
 	}
 
+	if err := recordRevision(ctx, tx, review); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit review save: %w", err)
+	}
+
+	InvalidateStatusCache(ctx, db, review.Letter)
+
 	return nil
 }
 
 // GetReviewStats returns review statistics
-func GetReviewStats(db *sql.DB) (total, reviewed, reviewedOK, reviewedCorrections int, err error) {
+func GetReviewStats(ctx context.Context, db *sql.DB) (total, reviewed, reviewedOK, reviewedCorrections int, err error) {
 	query := `
 		SELECT
 			COUNT(*) as total,
@@ -186,47 +361,288 @@ func GetReviewStats(db *sql.DB) (total, reviewed, reviewedOK, reviewedCorrection
 		FROM reviews
 	`
 
-	err = db.QueryRow(query).Scan(&total, &reviewed, &reviewedOK, &reviewedCorrections)
+	err = db.QueryRowContext(ctx, query).Scan(&total, &reviewed, &reviewedOK, &reviewedCorrections)
 	return
 }
 
-// FindLemmaByID finds a lemma by its ID
-func FindLemmaByID(data *LemmaData, id int) *Lemma {
-	for i := range data.Lemmas {
-		if data.Lemmas[i].ID == id {
-			return &data.Lemmas[i]
+const lemmaColumns = `
+	id, lemma, entry_number, version, greek_text, english_translation,
+	type, volume_label, meineke_id, billerbeck_id, word_count,
+	image_filenames, confidence, letter, sort_order
+`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLemma(s rowScanner) (*Lemma, error) {
+	var lemma Lemma
+	var imageFilenamesJSON string
+
+	err := s.Scan(
+		&lemma.ID, &lemma.Lemma, &lemma.EntryNumber, &lemma.Version,
+		&lemma.GreekText, &lemma.EnglishTranslation, &lemma.Type,
+		&lemma.VolumeLabel, &lemma.MeinekeID, &lemma.BillerbeckID,
+		&lemma.WordCount, &imageFilenamesJSON, &lemma.Confidence,
+		&lemma.Letter, &lemma.SortOrder,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if imageFilenamesJSON != "" {
+		if err := json.Unmarshal([]byte(imageFilenamesJSON), &lemma.ImageFilenames); err != nil {
+			return nil, fmt.Errorf("failed to parse image_filenames: %w", err)
 		}
 	}
-	return nil
+
+	return &lemma, nil
 }
 
-// FindLemmaBySortOrder finds a lemma by its sort order
-func FindLemmaBySortOrder(data *LemmaData, sortOrder int) *Lemma {
-	if sortOrder < 0 || sortOrder >= len(data.Lemmas) {
+// ImportLemmas ingests the JSON lemma export at path into the `lemmas` table
+// (and its `lemmas_fts` FTS5 search index), so cold CGI invocations can query
+// the corpus over SQLite instead of re-parsing the whole export every time.
+// It is idempotent: if `lemmas` already has rows, it does nothing, so it's
+// cheap to call on every request and LoadLemmaData remains the source of
+// truth to (re-)import from.
+func ImportLemmas(ctx context.Context, db *sql.DB, path string) error {
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS lemmas (
+			id INTEGER PRIMARY KEY,
+			lemma TEXT,
+			entry_number INTEGER,
+			version TEXT,
+			greek_text TEXT,
+			english_translation TEXT,
+			type TEXT,
+			volume_label TEXT,
+			meineke_id TEXT,
+			billerbeck_id TEXT,
+			word_count INTEGER,
+			image_filenames TEXT,
+			confidence TEXT,
+			letter TEXT,
+			sort_order INTEGER
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create lemmas table: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS lemmas_sort_order_idx ON lemmas(sort_order)`); err != nil {
+		return fmt.Errorf("failed to create lemmas_sort_order_idx: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS lemmas_letter_idx ON lemmas(letter)`); err != nil {
+		return fmt.Errorf("failed to create lemmas_letter_idx: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, `
+		CREATE VIRTUAL TABLE IF NOT EXISTS lemmas_fts USING fts5(
+			greek_text, english_translation, content='lemmas', content_rowid='id'
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create lemmas_fts: %w", err)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM lemmas`).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count lemmas: %w", err)
+	}
+	if count > 0 {
 		return nil
 	}
-	return &data.Lemmas[sortOrder]
+
+	data, err := LoadLemmaData(path)
+	if err != nil {
+		return fmt.Errorf("failed to load lemma data: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin lemma import: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO lemmas (` + lemmaColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, json(?), ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare lemma import: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, lemma := range data.Lemmas {
+		imageFilenamesJSON, err := json.Marshal(lemma.ImageFilenames)
+		if err != nil {
+			return fmt.Errorf("failed to encode image_filenames for lemma %d: %w", lemma.ID, err)
+		}
+
+		if _, err := stmt.ExecContext(ctx,
+			lemma.ID, lemma.Lemma, lemma.EntryNumber, lemma.Version,
+			lemma.GreekText, lemma.EnglishTranslation, lemma.Type,
+			lemma.VolumeLabel, lemma.MeinekeID, lemma.BillerbeckID,
+			lemma.WordCount, string(imageFilenamesJSON), lemma.Confidence,
+			lemma.Letter, lemma.SortOrder,
+		); err != nil {
+			return fmt.Errorf("failed to insert lemma %d: %w", lemma.ID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO lemmas_fts(lemmas_fts) VALUES ('rebuild')`); err != nil {
+		return fmt.Errorf("failed to build lemmas_fts index: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// FindLemmaByID finds a lemma by its ID, querying the lemmas table (indexed
+// on its primary key) rather than scanning an in-memory slice.
+func FindLemmaByID(ctx context.Context, db *sql.DB, id int) (*Lemma, error) {
+	lemma, err := scanLemma(db.QueryRowContext(ctx, `SELECT `+lemmaColumns+` FROM lemmas WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lemma %d: %w", id, err)
+	}
+	return lemma, nil
+}
+
+// FindLemmaBySortOrder finds a lemma by its sort order, via the
+// lemmas_sort_order_idx index.
+func FindLemmaBySortOrder(ctx context.Context, db *sql.DB, sortOrder int) (*Lemma, error) {
+	lemma, err := scanLemma(db.QueryRowContext(ctx, `SELECT `+lemmaColumns+` FROM lemmas WHERE sort_order = ?`, sortOrder))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lemma at sort order %d: %w", sortOrder, err)
+	}
+	return lemma, nil
 }
 
 // GetNextUnreviewedInLetter finds next unreviewed lemma in the same letter
-func GetNextUnreviewedInLetter(db *sql.DB, data *LemmaData, currentLemma *Lemma) *Lemma {
-	// Start from current position and look forward
-	for i := currentLemma.SortOrder + 1; i < len(data.Lemmas); i++ {
+func GetNextUnreviewedInLetter(ctx context.Context, db *sql.DB, data *LemmaData, currentLemma *Lemma) *Lemma {
+	lemma, err := GetFirstUnreviewedInLetter(ctx, db, data, currentLemma.Letter, currentLemma.SortOrder)
+	if err != nil {
+		return nil
+	}
+	return lemma
+}
+
+// GetFirstUnreviewedInLetter finds the first lemma in letter whose sort_order
+// comes after afterSortOrder and that has no review row or a "not_reviewed"
+// one. It resolves the whole letter with a single bulk query
+// (GetReviewsForLemmas) rather than one GetReview round-trip per lemma.
+func GetFirstUnreviewedInLetter(ctx context.Context, db *sql.DB, data *LemmaData, letter string, afterSortOrder int) (*Lemma, error) {
+	var candidates []*Lemma
+	for i := range data.Lemmas {
 		lemma := &data.Lemmas[i]
+		if lemma.Letter != letter || lemma.SortOrder <= afterSortOrder {
+			continue
+		}
+		candidates = append(candidates, lemma)
+	}
+
+	ids := make([]int, len(candidates))
+	for i, lemma := range candidates {
+		ids[i] = lemma.ID
+	}
+
+	reviews, err := GetReviewsForLemmas(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
 
-		// Stop if we've moved to a different letter
-		if lemma.Letter != currentLemma.Letter {
-			break
+	for _, lemma := range candidates {
+		review, ok := reviews[lemma.ID]
+		if !ok || review.ReviewStatus == "not_reviewed" {
+			return lemma, nil
 		}
+	}
+
+	return nil, nil // No unreviewed entries in this letter
+}
 
-		// Check if this lemma is unreviewed
-		review, err := GetReview(db, lemma.ID)
-		if err == nil && review.ReviewStatus == "not_reviewed" {
-			return lemma
+// GetReviewsForLemmas bulk-loads review rows for ids in a single round trip
+// (chunked to stay under SQLite's ~999 bound-parameter limit), returning a
+// map keyed by lemma ID. Lemmas with no review row are simply absent from
+// the map, mirroring GetReview's "not_reviewed" default for callers that
+// check `ok` before falling back.
+func GetReviewsForLemmas(ctx context.Context, db *sql.DB, ids []int) (map[int]*Review, error) {
+	result := make(map[int]*Review, len(ids))
+
+	const chunkSize = 900
+	for start := 0; start < len(ids); start += chunkSize {
+		end := start + chunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			placeholders[i] = "?"
+			args[i] = id
+		}
+
+		query := fmt.Sprintf(`
+			SELECT lemma_id, review_status,
+			       COALESCE(corrected_greek_text, ''),
+			       COALESCE(corrected_english_translation, ''),
+			       COALESCE(reviewed_english_translation, ''),
+			       COALESCE(reviewer_username, ''),
+			       reviewed_at,
+			       COALESCE(notes, ''),
+			       COALESCE(greek_corrected_by, ''),
+			       COALESCE(initial_translation_by, ''),
+			       COALESCE(reviewed_translation_by, ''),
+			       COALESCE(ef, 2.5),
+			       COALESCE(interval_days, 0),
+			       COALESCE(repetitions, 0),
+			       due_at
+			FROM reviews
+			WHERE lemma_id IN (%s)
+		`, strings.Join(placeholders, ","))
+
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query reviews: %w", err)
+		}
+
+		scanErr := func() error {
+			defer rows.Close()
+			for rows.Next() {
+				review := &Review{}
+				if err := rows.Scan(
+					&review.LemmaID,
+					&review.ReviewStatus,
+					&review.CorrectedGreekText,
+					&review.CorrectedEnglishTranslation,
+					&review.ReviewedEnglishTranslation,
+					&review.ReviewerUsername,
+					&review.ReviewedAt,
+					&review.Notes,
+					&review.GreekCorrectedBy,
+					&review.InitialTranslationBy,
+					&review.ReviewedTranslationBy,
+					&review.EaseFactor,
+					&review.IntervalDays,
+					&review.Repetitions,
+					&review.DueAt,
+				); err != nil {
+					return fmt.Errorf("failed to scan review: %w", err)
+				}
+				result[review.LemmaID] = review
+			}
+			return rows.Err()
+		}()
+		if scanErr != nil {
+			return nil, scanErr
 		}
 	}
 
-	return nil // No unreviewed entries in this letter
+	return result, nil
 }
 
 // GetPreviousLemma returns the previous lemma in sort order
@@ -280,6 +696,29 @@ func GetGreekLetterName(letter string) string {
 	return letter
 }
 
+// redirectToLemma emits a 303 redirect (with an HTML fallback for clients
+// that don't follow it) back to the given lemma's review page. Shared by
+// every CGI entrypoint that ends in a redirect back to review.cgi
+// (save.cgi, annotate.cgi, history.cgi), since each is a separate `main`
+// built from its own source file plus the non-main files in this package.
+func redirectToLemma(lemmaID int) {
+	fmt.Println("Status: 303 See Other")
+	fmt.Printf("Location: /cgi-bin/review.cgi?id=%d\n", lemmaID)
+	fmt.Println("Content-Type: text/html; charset=utf-8")
+	fmt.Println()
+	fmt.Printf(`<!DOCTYPE html>
+<html>
+<head>
+    <meta http-equiv="refresh" content="0;url=/cgi-bin/review.cgi?id=%d">
+    <title>Redirecting...</title>
+</head>
+<body>
+    <p>Review saved. Redirecting...</p>
+    <p><a href="/cgi-bin/review.cgi?id=%d">Click here if not redirected</a></p>
+</body>
+</html>`, lemmaID, lemmaID)
+}
+
 // HTMLEscape escapes HTML special characters
 func HTMLEscape(s string) string {
 	s = strings.ReplaceAll(s, "&", "&amp;")
@@ -297,30 +736,35 @@ type LetterNav struct {
 	FirstID     int
 }
 
-// GetLetterNavigation returns navigation info for all letters
-func GetLetterNavigation(data *LemmaData) []LetterNav {
-	letterMap := make(map[string]int) // letter -> first ID
-	var letters []string
-
-	// Find first entry for each letter
-	for i := range data.Lemmas {
-		lemma := &data.Lemmas[i]
-		letter := lemma.Letter
-		if _, exists := letterMap[letter]; !exists {
-			letterMap[letter] = lemma.ID
-			letters = append(letters, letter)
-		}
+// GetLetterNavigation returns navigation info for all letters, queried from
+// the lemmas table (ordered by sort_order, which lemmas_sort_order_idx
+// backs) instead of scanning an in-memory slice.
+func GetLetterNavigation(ctx context.Context, db *sql.DB) ([]LetterNav, error) {
+	rows, err := db.QueryContext(ctx, `SELECT letter, id FROM lemmas ORDER BY sort_order ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query letters: %w", err)
 	}
+	defer rows.Close()
 
-	// Build navigation list
+	seen := make(map[string]bool)
 	var nav []LetterNav
-	for _, letter := range letters {
+
+	for rows.Next() {
+		var letter string
+		var id int
+		if err := rows.Scan(&letter, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan letter row: %w", err)
+		}
+		if seen[letter] {
+			continue
+		}
+		seen[letter] = true
 		nav = append(nav, LetterNav{
 			Letter:      letter,
 			DisplayName: GetGreekLetterName(letter),
-			FirstID:     letterMap[letter],
+			FirstID:     id,
 		})
 	}
 
-	return nav
+	return nav, rows.Err()
 }
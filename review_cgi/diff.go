@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// nfc normalizes combining marks to their precomposed (NFC) form. Polytonic
+// Greek can represent the same accented letter as either a single
+// precomposed code point or a base letter plus combining diacritics; without
+// normalizing first, diffs would flag these as changes even when nothing
+// actually changed.
+func nfc(s string) string {
+	return norm.NFC.String(s)
+}
+
+// DiffOp is a single operation in a token-level diff: text that is
+// unchanged, inserted, or deleted when going from the "a" side to the "b"
+// side.
+type DiffOp struct {
+	Type string // "equal", "insert", "delete"
+	Text string
+}
+
+// diffWords computes a minimal word-level diff between two whitespace-split
+// token sequences using the Myers algorithm.
+func diffWords(a, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	v := map[int]int{1: 0}
+	var trace []map[int]int
+
+	for d := 0; d <= max; d++ {
+		snapshot := make(map[int]int, len(v))
+		for k, val := range v {
+			snapshot[k] = val
+		}
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1] < v[k+1]) {
+				x = v[k+1]
+			} else {
+				x = v[k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace, d)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrackDiff(a, b []string, trace []map[int]int, d int) []DiffOp {
+	x, y := len(a), len(b)
+	var ops []DiffOp
+
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k-1] < v[k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, DiffOp{Type: "equal", Text: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, DiffOp{Type: "insert", Text: b[y-1]})
+			y--
+		} else {
+			ops = append(ops, DiffOp{Type: "delete", Text: a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, DiffOp{Type: "equal", Text: a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// diffText tokenises two strings on whitespace and returns their word-level
+// diff. Callers are expected to NFC-normalize Greek text first, so
+// precomposed and decomposed accents don't show up as spurious changes.
+func diffText(a, b string) []DiffOp {
+	return diffWords(strings.Fields(a), strings.Fields(b))
+}
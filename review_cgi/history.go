@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// HistoryPageData holds data for history.cgi's template rendering.
+type HistoryPageData struct {
+	LemmaID   int
+	Revisions []Revision
+	FromID    int
+	ToID      int
+	Diff      *RevisionDiff
+}
+
+// history.cgi lists a lemma's revisions newest-first, renders a word-level
+// diff between two selected revisions, and handles reverting to a prior one.
+func main() {
+	if os.Getenv("REQUEST_METHOD") == "POST" {
+		handleRevert()
+		return
+	}
+
+	queryString := os.Getenv("QUERY_STRING")
+	params, err := url.ParseQuery(queryString)
+	if err != nil {
+		showHistoryError(fmt.Sprintf("Failed to parse query: %v", err))
+		return
+	}
+
+	lemmaID, err := strconv.Atoi(params.Get("id"))
+	if err != nil {
+		showHistoryError("Missing or invalid lemma id")
+		return
+	}
+
+	config := GetConfig()
+	ctx, cancel := RequestContext(config)
+	defer cancel()
+
+	db, err := OpenDatabase(ctx, config.DBPath)
+	if err != nil {
+		showHistoryError(fmt.Sprintf("Failed to open database: %v", err))
+		return
+	}
+	defer db.Close()
+
+	revisions, err := GetReviewHistory(ctx, db, lemmaID)
+	if err != nil {
+		showHistoryError(fmt.Sprintf("Failed to load history: %v", err))
+		return
+	}
+
+	pageData := HistoryPageData{LemmaID: lemmaID, Revisions: revisions}
+
+	fromStr, toStr := params.Get("from"), params.Get("to")
+	if fromStr != "" && toStr != "" {
+		fromID, err1 := strconv.Atoi(fromStr)
+		toID, err2 := strconv.Atoi(toStr)
+		if err1 == nil && err2 == nil {
+			fromRev, err := GetRevision(ctx, db, fromID)
+			if err != nil {
+				showHistoryError(fmt.Sprintf("Failed to load revision: %v", err))
+				return
+			}
+			toRev, err := GetRevision(ctx, db, toID)
+			if err != nil {
+				showHistoryError(fmt.Sprintf("Failed to load revision: %v", err))
+				return
+			}
+			diff := DiffRevisions(fromRev, toRev)
+			pageData.FromID = fromID
+			pageData.ToID = toID
+			pageData.Diff = &diff
+		}
+	}
+
+	tmpl, err := template.New("history").Parse(historyTemplate)
+	if err != nil {
+		showHistoryError(fmt.Sprintf("Template error: %v", err))
+		return
+	}
+
+	fmt.Println("Content-Type: text/html; charset=utf-8")
+	fmt.Println()
+	if err := tmpl.Execute(os.Stdout, pageData); err != nil {
+		log.Printf("Template execution error: %v", err)
+	}
+}
+
+// handleRevert writes a new revision copying a prior one's fields, so
+// reverting never loses the audit trail.
+func handleRevert() {
+	contentLength := os.Getenv("CONTENT_LENGTH")
+	length, err := strconv.Atoi(contentLength)
+	if err != nil || length <= 0 {
+		showHistoryError("Invalid content length")
+		return
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(os.Stdin, body); err != nil {
+		showHistoryError(fmt.Sprintf("Failed to read POST data: %v", err))
+		return
+	}
+	formData, err := url.ParseQuery(string(body))
+	if err != nil {
+		showHistoryError(fmt.Sprintf("Failed to parse form data: %v", err))
+		return
+	}
+
+	lemmaID, err := strconv.Atoi(formData.Get("lemma_id"))
+	if err != nil {
+		showHistoryError("Invalid lemma ID")
+		return
+	}
+	revisionID, err := strconv.Atoi(formData.Get("revision_id"))
+	if err != nil {
+		showHistoryError("Invalid revision ID")
+		return
+	}
+	remoteUser := os.Getenv("REMOTE_USER")
+
+	config := GetConfig()
+	ctx, cancel := RequestContext(config)
+	defer cancel()
+
+	db, err := OpenDatabase(ctx, config.DBPath)
+	if err != nil {
+		showHistoryError(fmt.Sprintf("Failed to open database: %v", err))
+		return
+	}
+	defer db.Close()
+
+	if err := ImportLemmas(ctx, db, config.DataFile); err != nil {
+		showHistoryError(fmt.Sprintf("Failed to import lemma data: %v", err))
+		return
+	}
+
+	revision, err := GetRevision(ctx, db, revisionID)
+	if err != nil {
+		showHistoryError(fmt.Sprintf("Failed to load revision: %v", err))
+		return
+	}
+	if revision.LemmaID != lemmaID {
+		showHistoryError("Revision does not belong to this lemma")
+		return
+	}
+
+	oldReview, err := GetReview(ctx, db, lemmaID)
+	if err != nil {
+		showHistoryError(fmt.Sprintf("Failed to get existing review: %v", err))
+		return
+	}
+
+	reverted := &Review{
+		LemmaID:                     lemmaID,
+		ReviewStatus:                revision.ReviewStatus,
+		CorrectedGreekText:          revision.CorrectedGreek,
+		CorrectedEnglishTranslation: revision.CorrectedEnglish,
+		ReviewedEnglishTranslation:  revision.ReviewedEnglish,
+		ReviewerUsername:            remoteUser,
+		Notes:                       revision.Notes,
+		GreekCorrectedBy:            oldReview.GreekCorrectedBy,
+		InitialTranslationBy:        oldReview.InitialTranslationBy,
+		ReviewedTranslationBy:       oldReview.ReviewedTranslationBy,
+		EaseFactor:                  oldReview.EaseFactor,
+		IntervalDays:                oldReview.IntervalDays,
+		Repetitions:                 oldReview.Repetitions,
+		DueAt:                       oldReview.DueAt,
+	}
+	if lemma, err := FindLemmaByID(ctx, db, lemmaID); err == nil && lemma != nil {
+		reverted.Letter = lemma.Letter
+	}
+
+	if err := SaveReview(ctx, db, reverted, oldReview, remoteUser); err != nil {
+		showHistoryError(fmt.Sprintf("Failed to revert: %v", err))
+		return
+	}
+
+	log.Printf("Reverted lemma %d to revision %d by %s", lemmaID, revisionID, remoteUser)
+	redirectToLemma(lemmaID)
+}
+
+func showHistoryError(message string) {
+	fmt.Println("Content-Type: text/html; charset=utf-8")
+	fmt.Println()
+	fmt.Printf(`<!DOCTYPE html>
+<html>
+<head><title>Error - History</title></head>
+<body>
+    <h1>Error</h1>
+    <p>%s</p>
+</body>
+</html>`, HTMLEscape(message))
+	log.Printf("Error: %s", message)
+}
@@ -0,0 +1,374 @@
+// iiif.cgi - minimal IIIF Image API 2.1 endpoint for source page scans.
+//
+// Serves /{filename}/info.json and /{filename}/{region}/{size}/{rotation}/{quality}.{format}
+// tiles generated on demand from the original scan under Config.ImagesDir,
+// so reviewers can deep-zoom into high-resolution images without
+// downloading the full original. Generated tiles are cached on disk under
+// Config.TileCacheDir, keyed by the request path.
+//
+// This implements the subset of the spec OpenSeadragon actually exercises:
+// pixel and percentage regions, pixel and percentage sizes, 90-degree
+// rotations, and default/gray quality. Arbitrary-angle rotation and
+// bitonal quality are not supported.
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/cgi"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+func main() {
+	config := GetConfig()
+	cgi.Serve(WithAccessLog(config, http.HandlerFunc(handleIIIF)))
+}
+
+// IIIFInfo is the JSON body of a IIIF 2.1 info.json response.
+type IIIFInfo struct {
+	Context  string      `json:"@context"`
+	ID       string      `json:"@id"`
+	Protocol string      `json:"protocol"`
+	Width    int         `json:"width"`
+	Height   int         `json:"height"`
+	Profile  []string    `json:"profile"`
+	Tiles    []IIIFTiles `json:"tiles"`
+}
+
+// IIIFTiles describes one tile size and the scale factors available at it.
+type IIIFTiles struct {
+	Width        int   `json:"width"`
+	ScaleFactors []int `json:"scaleFactors"`
+}
+
+const tileSize = 512
+
+func handleIIIF(w http.ResponseWriter, r *http.Request) {
+	config := GetConfig()
+
+	// Path is "/{filename}/info.json" or "/{filename}/{region}/{size}/{rotation}/{quality}.{format}"
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) < 2 {
+		http.Error(w, "invalid IIIF request path", http.StatusBadRequest)
+		return
+	}
+	filename := parts[0]
+	rest := parts[1]
+
+	sourcePath := filepath.Join(config.ImagesDir, filepath.Base(filename))
+	srcImg, err := loadImage(sourcePath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to load source image: %v", err), http.StatusNotFound)
+		return
+	}
+	bounds := srcImg.Bounds()
+	fullW, fullH := bounds.Dx(), bounds.Dy()
+
+	if rest == "info.json" {
+		writeInfoJSON(w, r, filename, fullW, fullH)
+		return
+	}
+
+	tile, err := renderTile(srcImg, rest, config, filename)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid IIIF tile request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	format := rest[strings.LastIndex(rest, ".")+1:]
+	switch format {
+	case "png":
+		w.Header().Set("Content-Type", "image/png")
+	default:
+		w.Header().Set("Content-Type", "image/jpeg")
+	}
+	w.Write(tile)
+}
+
+func writeInfoJSON(w http.ResponseWriter, r *http.Request, filename string, width, height int) {
+	var scaleFactors []int
+	for sf := 1; tileSize*sf < width || tileSize*sf < height; sf *= 2 {
+		scaleFactors = append(scaleFactors, sf)
+	}
+	scaleFactors = append([]int{1}, scaleFactors...)
+
+	info := IIIFInfo{
+		Context:  "http://iiif.io/api/image/2/context.json",
+		ID:       fmt.Sprintf("%s://%s/cgi-bin/iiif.cgi/%s", schemeOf(r), r.Host, filename),
+		Protocol: "http://iiif.io/api/image",
+		Width:    width,
+		Height:   height,
+		Profile:  []string{"http://iiif.io/api/image/2/level1.json"},
+		Tiles:    []IIIFTiles{{Width: tileSize, ScaleFactors: scaleFactors}},
+	}
+
+	w.Header().Set("Content-Type", "application/ld+json")
+	json.NewEncoder(w).Encode(info)
+}
+
+func schemeOf(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// renderTile serves a single IIIF tile, reading it from the disk cache if
+// present and writing a freshly generated one back to the cache otherwise.
+func renderTile(src image.Image, spec string, config Config, filename string) ([]byte, error) {
+	cacheKey := sha1.Sum([]byte(filename + "/" + spec))
+	cachePath := filepath.Join(config.TileCacheDir, hex.EncodeToString(cacheKey[:])+filepath.Ext(spec))
+
+	if cached, err := os.ReadFile(cachePath); err == nil {
+		return cached, nil
+	}
+
+	segments := strings.SplitN(spec, "/", 4)
+	if len(segments) != 4 {
+		return nil, fmt.Errorf("expected region/size/rotation/quality.format, got %q", spec)
+	}
+	regionSpec, sizeSpec, rotationSpec := segments[0], segments[1], segments[2]
+	qualityAndFormat := segments[3]
+	dot := strings.LastIndex(qualityAndFormat, ".")
+	if dot < 0 {
+		return nil, fmt.Errorf("missing format extension in %q", qualityAndFormat)
+	}
+	quality, format := qualityAndFormat[:dot], qualityAndFormat[dot+1:]
+
+	region, err := parseRegion(regionSpec, src.Bounds().Dx(), src.Bounds().Dy())
+	if err != nil {
+		return nil, err
+	}
+	cropped := cropImage(src, region)
+
+	outW, outH, err := parseSize(sizeSpec, region.Dx(), region.Dy())
+	if err != nil {
+		return nil, err
+	}
+	scaled := scaleImage(cropped, outW, outH)
+
+	rotated, err := rotateImage(scaled, rotationSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	final := applyQuality(rotated, quality)
+
+	data, err := encodeImage(final, format)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(config.TileCacheDir, 0o755); err == nil {
+		os.WriteFile(cachePath, data, 0o644)
+	}
+
+	return data, nil
+}
+
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// parseRegion supports "full", "square", pixel "x,y,w,h", and percentage
+// "pct:x,y,w,h" region specs.
+func parseRegion(spec string, fullW, fullH int) (image.Rectangle, error) {
+	if spec == "full" {
+		return image.Rect(0, 0, fullW, fullH), nil
+	}
+	if spec == "square" {
+		side := fullW
+		if fullH < side {
+			side = fullH
+		}
+		x := (fullW - side) / 2
+		y := (fullH - side) / 2
+		return image.Rect(x, y, x+side, y+side), nil
+	}
+
+	pct := strings.HasPrefix(spec, "pct:")
+	values := strings.TrimPrefix(spec, "pct:")
+	nums, err := parseFourInts(values)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	x, y, rw, rh := nums[0], nums[1], nums[2], nums[3]
+	if pct {
+		x = x * fullW / 100
+		y = y * fullH / 100
+		rw = rw * fullW / 100
+		rh = rh * fullH / 100
+	}
+	return image.Rect(x, y, x+rw, y+rh), nil
+}
+
+// parseSize supports "full", "w,", ",h", and "w,h" pixel size specs, plus
+// "pct:n".
+func parseSize(spec string, regionW, regionH int) (int, int, error) {
+	if spec == "full" || spec == "max" {
+		return regionW, regionH, nil
+	}
+	if strings.HasPrefix(spec, "pct:") {
+		pct, err := strconv.ParseFloat(strings.TrimPrefix(spec, "pct:"), 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid size percentage: %w", err)
+		}
+		return int(float64(regionW) * pct / 100), int(float64(regionH) * pct / 100), nil
+	}
+
+	parts := strings.SplitN(spec, ",", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid size spec %q", spec)
+	}
+	w, h := parts[0], parts[1]
+	switch {
+	case w != "" && h != "":
+		wi, err1 := strconv.Atoi(w)
+		hi, err2 := strconv.Atoi(h)
+		if err1 != nil || err2 != nil {
+			return 0, 0, fmt.Errorf("invalid size spec %q", spec)
+		}
+		return wi, hi, nil
+	case w != "":
+		wi, err := strconv.Atoi(w)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid size spec %q", spec)
+		}
+		return wi, regionH * wi / regionW, nil
+	case h != "":
+		hi, err := strconv.Atoi(h)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid size spec %q", spec)
+		}
+		return regionW * hi / regionH, hi, nil
+	default:
+		return 0, 0, fmt.Errorf("invalid size spec %q", spec)
+	}
+}
+
+func parseFourInts(s string) ([4]int, error) {
+	var out [4]int
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return out, fmt.Errorf("expected 4 comma-separated values, got %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return out, fmt.Errorf("invalid number %q: %w", p, err)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+func cropImage(src image.Image, region image.Rectangle) image.Image {
+	region = region.Intersect(src.Bounds())
+	if sub, ok := src.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(region)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, region.Dx(), region.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, region.Min, draw.Src)
+	return dst
+}
+
+func scaleImage(src image.Image, w, h int) image.Image {
+	if w <= 0 || h <= 0 || (w == src.Bounds().Dx() && h == src.Bounds().Dy()) {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+	return dst
+}
+
+// rotateImage supports the 90-degree rotations IIIF level-1 servers are
+// required to provide; an arbitrary angle is rejected.
+func rotateImage(src image.Image, spec string) (image.Image, error) {
+	degrees, err := strconv.Atoi(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rotation %q: %w", spec, err)
+	}
+	b := src.Bounds()
+	switch ((degrees % 360) + 360) % 360 {
+	case 0:
+		return src, nil
+	case 90:
+		dst := image.NewRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				dst.Set(b.Max.Y-1-y, x-b.Min.X, src.At(x, y))
+			}
+		}
+		return dst, nil
+	case 180:
+		dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				dst.Set(b.Max.X-1-x, b.Max.Y-1-y, src.At(x, y))
+			}
+		}
+		return dst, nil
+	case 270:
+		dst := image.NewRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				dst.Set(y-b.Min.Y, b.Max.X-1-x, src.At(x, y))
+			}
+		}
+		return dst, nil
+	default:
+		return nil, fmt.Errorf("unsupported rotation %q (only multiples of 90 are supported)", spec)
+	}
+}
+
+func applyQuality(src image.Image, quality string) image.Image {
+	if quality != "gray" {
+		return src
+	}
+	b := src.Bounds()
+	dst := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(x, y, color.GrayModel.Convert(src.At(x, y)))
+		}
+	}
+	return dst
+}
+
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+	default:
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
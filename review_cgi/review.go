@@ -7,22 +7,31 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"time"
 )
 
 // PageData holds data for template rendering
 type PageData struct {
-	Lemma             *Lemma
-	Review            *Review
-	TotalCount        int
-	ReviewedCount     int
-	PercentComplete   int
-	CurrentPosition   int
-	HasPrevious       bool
-	HasNext           bool
-	PreviousID        int
-	NextID            int
-	HasNextUnreviewed bool
-	LetterName        string
+	Lemma               *Lemma
+	Review              *Review
+	TotalCount          int
+	ReviewedCount       int
+	PercentComplete     int
+	CurrentPosition     int
+	HasPrevious         bool
+	HasNext             bool
+	PreviousID          int
+	NextID              int
+	HasNextUnreviewed   bool
+	LetterName          string
+	DueSoon             bool
+	ApprovalStatus      string
+	Events              []ReviewEvent
+	RemoteUser          string
+	Annotations         []Annotation
+	OpenAnnotationCount int
+	ShowResolved        bool
+	LatestRevision      *Revision
 }
 
 func main() {
@@ -32,6 +41,8 @@ func main() {
 
 	// Load configuration
 	config := GetConfig()
+	ctx, cancel := RequestContext(config)
+	defer cancel()
 
 	// Load lemma data
 	data, err := LoadLemmaData(config.DataFile)
@@ -41,13 +52,18 @@ func main() {
 	}
 
 	// Open database
-	db, err := OpenDatabase(config.DBPath)
+	db, err := OpenDatabase(ctx, config.DBPath)
 	if err != nil {
 		showError(fmt.Sprintf("Failed to open database: %v", err))
 		return
 	}
 	defer db.Close()
 
+	if err := ImportLemmas(ctx, db, config.DataFile); err != nil {
+		showError(fmt.Sprintf("Failed to import lemma data: %v", err))
+		return
+	}
+
 	// Parse query parameters
 	queryString := os.Getenv("QUERY_STRING")
 	params, err := url.ParseQuery(queryString)
@@ -66,17 +82,48 @@ func main() {
 	if action == "next_unreviewed" && lemmaIDStr != "" {
 		// Find next unreviewed in same letter
 		lemmaID, _ := strconv.Atoi(lemmaIDStr)
-		currentLemma = FindLemmaByID(data, lemmaID)
+		currentLemma, _ = FindLemmaByID(ctx, db, lemmaID)
 		if currentLemma != nil {
-			nextUnreviewed := GetNextUnreviewedInLetter(db, data, currentLemma)
+			nextUnreviewed := GetNextUnreviewedInLetter(ctx, db, data, currentLemma)
 			if nextUnreviewed != nil {
 				currentLemma = nextUnreviewed
 			}
 		}
+	} else if action == "next_due" {
+		// Find the lemma most overdue for review under the SM-2 schedule,
+		// scoped to the current letter if one was given.
+		letter := ""
+		if lemmaIDStr != "" {
+			lemmaID, _ := strconv.Atoi(lemmaIDStr)
+			if fromLemma, _ := FindLemmaByID(ctx, db, lemmaID); fromLemma != nil {
+				letter = fromLemma.Letter
+			}
+		}
+		scheduled, err := GetNextScheduledLemma(ctx, db, data, letter)
+		if err != nil {
+			showError(fmt.Sprintf("Failed to compute schedule: %v", err))
+			return
+		}
+		currentLemma = scheduled
+	} else if action == "next_with_open_annotations" && lemmaIDStr != "" {
+		lemmaID, _ := strconv.Atoi(lemmaIDStr)
+		fromLemma, _ := FindLemmaByID(ctx, db, lemmaID)
+		if fromLemma != nil {
+			next, err := GetNextWithOpenAnnotations(ctx, db, data, fromLemma)
+			if err != nil {
+				showError(fmt.Sprintf("Failed to find next annotated lemma: %v", err))
+				return
+			}
+			if next != nil {
+				currentLemma = next
+			} else {
+				currentLemma = fromLemma
+			}
+		}
 	} else if lemmaIDStr != "" {
 		// Specific lemma requested
 		lemmaID, _ := strconv.Atoi(lemmaIDStr)
-		currentLemma = FindLemmaByID(data, lemmaID)
+		currentLemma, _ = FindLemmaByID(ctx, db, lemmaID)
 	}
 
 	// If no lemma found, start with first lemma
@@ -90,14 +137,14 @@ func main() {
 	}
 
 	// Get review data
-	review, err := GetReview(db, currentLemma.ID)
+	review, err := GetReview(ctx, db, currentLemma.ID)
 	if err != nil {
 		showError(fmt.Sprintf("Failed to get review: %v", err))
 		return
 	}
 
 	// Get review stats
-	total, reviewed, _, _, err := GetReviewStats(db)
+	total, reviewed, _, _, err := GetReviewStats(ctx, db)
 	if err != nil {
 		showError(fmt.Sprintf("Failed to get review stats: %v", err))
 		return
@@ -106,11 +153,15 @@ func main() {
 	// If total is 0, initialize all lemmas in reviews table
 	if total == 0 {
 		for _, lemma := range data.Lemmas {
+			dueNow := time.Now()
 			defaultReview := &Review{
 				LemmaID:      lemma.ID,
 				ReviewStatus: "not_reviewed",
+				EaseFactor:   2.5,
+				Letter:       lemma.Letter,
+				DueAt:        &dueNow,
 			}
-			SaveReview(db, defaultReview)
+			SaveReview(ctx, db, defaultReview, defaultReview, "")
 		}
 		total = len(data.Lemmas)
 		reviewed = 0
@@ -124,19 +175,61 @@ func main() {
 	// Navigation
 	prevLemma := GetPreviousLemma(data, currentLemma)
 	nextLemma := GetNextLemma(data, currentLemma)
-	nextUnreviewed := GetNextUnreviewedInLetter(db, data, currentLemma)
+	nextUnreviewed := GetNextUnreviewedInLetter(ctx, db, data, currentLemma)
+
+	remoteUser := os.Getenv("REMOTE_USER")
+	events, err := GetReviewEvents(ctx, db, currentLemma.ID, remoteUser)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to load review events: %v", err))
+		return
+	}
+	approvalStatus, err := ApprovalStatus(ctx, db, currentLemma.ID, config.RequiredApprovals)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to compute approval status: %v", err))
+		return
+	}
+
+	showResolved := params.Get("show_resolved") == "1"
+	annotations, err := GetAnnotationsForLemma(ctx, db, currentLemma.ID)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to load annotations: %v", err))
+		return
+	}
+	openAnnotationCount, err := CountOpenAnnotations(ctx, db, currentLemma.ID)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to count open annotations: %v", err))
+		return
+	}
+
+	var latestRevision *Revision
+	history, err := GetReviewHistory(ctx, db, currentLemma.ID)
+	if err != nil {
+		showError(fmt.Sprintf("Failed to load revision history: %v", err))
+		return
+	}
+	if len(history) > 0 {
+		latestRevision = &history[0]
+	}
 
 	pageData := PageData{
-		Lemma:             currentLemma,
-		Review:            review,
-		TotalCount:        len(data.Lemmas),
-		ReviewedCount:     reviewed,
-		PercentComplete:   percentComplete,
-		CurrentPosition:   currentLemma.SortOrder + 1,
-		HasPrevious:       prevLemma != nil,
-		HasNext:           nextLemma != nil,
-		HasNextUnreviewed: nextUnreviewed != nil,
-		LetterName:        GetGreekLetterName(currentLemma.Letter),
+		Lemma:               currentLemma,
+		Review:              review,
+		TotalCount:          len(data.Lemmas),
+		ReviewedCount:       reviewed,
+		PercentComplete:     percentComplete,
+		CurrentPosition:     currentLemma.SortOrder + 1,
+		HasPrevious:         prevLemma != nil,
+		HasNext:             nextLemma != nil,
+		HasNextUnreviewed:   nextUnreviewed != nil,
+		LetterName:          GetGreekLetterName(currentLemma.Letter),
+		DueSoon:             review.DueAt != nil && !review.DueAt.After(time.Now()),
+		ApprovalStatus:      approvalStatus,
+		Events:              events,
+		RemoteUser:          remoteUser,
+		Annotations:         annotations,
+		OpenAnnotationCount: openAnnotationCount,
+		ShowResolved:        showResolved,
+		LatestRevision:      latestRevision,
 	}
 
 	if prevLemma != nil {
@@ -147,7 +240,9 @@ func main() {
 	}
 
 	// Render template
-	tmpl, err := template.New("review").Parse(reviewTemplate)
+	tmpl, err := template.New("review").Funcs(template.FuncMap{
+		"annotateText": annotateHTML,
+	}).Parse(reviewTemplate)
 	if err != nil {
 		showError(fmt.Sprintf("Template error: %v", err))
 		return
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Revision is one snapshot of a lemma's review state, written every time
+// SaveReview runs so prior reviewer edits are never silently lost.
+type Revision struct {
+	ID               int       `json:"id"`
+	LemmaID          int       `json:"lemma_id"`
+	RevisionNo       int       `json:"revision_no"`
+	ParentRevisionNo int       `json:"parent_revision_no,omitempty"`
+	Reviewer         string    `json:"reviewer"`
+	CreatedAt        time.Time `json:"created_at"`
+	ReviewStatus     string    `json:"review_status"`
+	CorrectedGreek   string    `json:"corrected_greek"`
+	CorrectedEnglish string    `json:"corrected_english"`
+	ReviewedEnglish  string    `json:"reviewed_english"`
+	Notes            string    `json:"notes,omitempty"`
+}
+
+// schemaExecer is satisfied by both *sql.DB and *sql.Tx, so ensureRevisionColumns
+// can run either inside SaveReview's transaction or standalone.
+type schemaExecer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// ensureRevisionColumns adds revision_no/parent_revision_no to
+// review_revisions if they're missing, creating the table first via
+// ensureRevisionsTable if this is the first call to touch it at all.
+func ensureRevisionColumns(ctx context.Context, db schemaExecer) error {
+	if err := ensureRevisionsTable(ctx, db); err != nil {
+		return err
+	}
+	for _, column := range []string{"revision_no", "parent_revision_no"} {
+		has, err := hasColumn(ctx, db, "review_revisions", column)
+		if err != nil {
+			return err
+		}
+		if has {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE review_revisions ADD COLUMN %s INTEGER`, column)); err != nil {
+			return fmt.Errorf("failed to add review_revisions.%s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+func hasColumn(ctx context.Context, db schemaExecer, table, column string) (bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dfltValue interface{}
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan %s column info: %w", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// ensureRevisionsTable creates review_revisions if it doesn't already
+// exist. Unlike reviews (part of the pre-existing schema), review_revisions
+// is introduced by this feature, so nothing else creates it.
+func ensureRevisionsTable(ctx context.Context, db schemaExecer) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS review_revisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			lemma_id INTEGER NOT NULL,
+			reviewer TEXT,
+			created_at TIMESTAMP,
+			review_status TEXT,
+			corrected_greek TEXT,
+			corrected_english TEXT,
+			reviewed_english TEXT,
+			notes TEXT
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create review_revisions table: %w", err)
+	}
+	return nil
+}
+
+// recordRevision inserts a new revision row for review, normally called
+// from within the same transaction as the SaveReview upsert. Revisions form
+// a chain per lemma: revision_no increments from the lemma's current high
+// water mark, and parent_revision_no records what it superseded (0 for a
+// lemma's first revision).
+func recordRevision(ctx context.Context, tx *sql.Tx, review *Review) error {
+	if err := ensureRevisionColumns(ctx, tx); err != nil {
+		return err
+	}
+
+	var parentRevisionNo int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(revision_no), 0) FROM review_revisions WHERE lemma_id = ?`, review.LemmaID).Scan(&parentRevisionNo); err != nil {
+		return fmt.Errorf("failed to compute next revision number: %w", err)
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO review_revisions (
+			lemma_id, revision_no, parent_revision_no, reviewer, created_at, review_status,
+			corrected_greek, corrected_english, reviewed_english, notes
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		review.LemmaID,
+		parentRevisionNo+1,
+		sqlNullIfZero(parentRevisionNo),
+		review.ReviewerUsername,
+		time.Now(),
+		review.ReviewStatus,
+		review.CorrectedGreekText,
+		review.CorrectedEnglishTranslation,
+		review.ReviewedEnglishTranslation,
+		review.Notes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// sqlNullIfZero turns a 0 parent revision number (meaning "no parent, this
+// is the lemma's first revision") into a SQL NULL rather than a 0 row ID.
+func sqlNullIfZero(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// GetReviewHistory returns a lemma's revisions, newest first.
+func GetReviewHistory(ctx context.Context, db *sql.DB, lemmaID int) ([]Revision, error) {
+	if err := ensureRevisionColumns(ctx, db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, lemma_id, COALESCE(revision_no, 0), COALESCE(parent_revision_no, 0),
+		       reviewer, created_at, review_status,
+		       COALESCE(corrected_greek, ''), COALESCE(corrected_english, ''),
+		       COALESCE(reviewed_english, ''), COALESCE(notes, '')
+		FROM review_revisions
+		WHERE lemma_id = ?
+		ORDER BY created_at DESC
+	`, lemmaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review history: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		var r Revision
+		if err := rows.Scan(&r.ID, &r.LemmaID, &r.RevisionNo, &r.ParentRevisionNo, &r.Reviewer, &r.CreatedAt, &r.ReviewStatus,
+			&r.CorrectedGreek, &r.CorrectedEnglish, &r.ReviewedEnglish, &r.Notes); err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %w", err)
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
+// GetRevision fetches a single revision by ID.
+func GetRevision(ctx context.Context, db *sql.DB, revisionID int) (*Revision, error) {
+	if err := ensureRevisionColumns(ctx, db); err != nil {
+		return nil, err
+	}
+
+	var r Revision
+	err := db.QueryRowContext(ctx, `
+		SELECT id, lemma_id, COALESCE(revision_no, 0), COALESCE(parent_revision_no, 0),
+		       reviewer, created_at, review_status,
+		       COALESCE(corrected_greek, ''), COALESCE(corrected_english, ''),
+		       COALESCE(reviewed_english, ''), COALESCE(notes, '')
+		FROM review_revisions
+		WHERE id = ?
+	`, revisionID).Scan(&r.ID, &r.LemmaID, &r.RevisionNo, &r.ParentRevisionNo, &r.Reviewer, &r.CreatedAt, &r.ReviewStatus,
+		&r.CorrectedGreek, &r.CorrectedEnglish, &r.ReviewedEnglish, &r.Notes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load revision: %w", err)
+	}
+	return &r, nil
+}
+
+// RevisionSummary is the per-lemma aggregate status.cgi surfaces alongside
+// each lemma's review status: how many revisions exist and who wrote the
+// most recent one.
+type RevisionSummary struct {
+	Count        int
+	LastReviewer string
+}
+
+// GetRevisionSummariesForLemmas bulk-loads revision counts and the most
+// recent reviewer for ids in a single round trip (chunked like
+// GetReviewsForLemmas, to stay under SQLite's ~999 bound-parameter limit),
+// so status.cgi doesn't issue one review_revisions query per lemma. Lemmas
+// with no revisions are simply absent from the returned map.
+func GetRevisionSummariesForLemmas(ctx context.Context, db *sql.DB, ids []int) (map[int]RevisionSummary, error) {
+	if err := ensureRevisionColumns(ctx, db); err != nil {
+		return nil, err
+	}
+
+	result := make(map[int]RevisionSummary, len(ids))
+
+	const chunkSize = 900
+	for start := 0; start < len(ids); start += chunkSize {
+		end := start + chunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			placeholders[i] = "?"
+			args[i] = id
+		}
+
+		query := fmt.Sprintf(`
+			SELECT lemma_id, reviewer,
+			       COUNT(*) OVER (PARTITION BY lemma_id) AS revision_count,
+			       ROW_NUMBER() OVER (PARTITION BY lemma_id ORDER BY created_at DESC) AS rank
+			FROM review_revisions
+			WHERE lemma_id IN (%s)
+		`, strings.Join(placeholders, ","))
+
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query revision summaries: %w", err)
+		}
+
+		scanErr := func() error {
+			defer rows.Close()
+			for rows.Next() {
+				var lemmaID, revisionCount, rank int
+				var reviewer string
+				if err := rows.Scan(&lemmaID, &reviewer, &revisionCount, &rank); err != nil {
+					return fmt.Errorf("failed to scan revision summary: %w", err)
+				}
+				summary := result[lemmaID]
+				summary.Count = revisionCount
+				if rank == 1 {
+					summary.LastReviewer = reviewer
+				}
+				result[lemmaID] = summary
+			}
+			return rows.Err()
+		}()
+		if scanErr != nil {
+			return nil, scanErr
+		}
+	}
+
+	return result, nil
+}
+
+// RevisionDiff holds the word-level diffs between two revisions' Greek and
+// English fields, for rendering in history.cgi. history.cgi shows each field
+// as one inline run of text with insertions and deletions marked; the two
+// revisions are not shown side by side.
+type RevisionDiff struct {
+	Greek   []DiffOp
+	English []DiffOp
+}
+
+// DiffRevisions computes the word-level diff between two revisions. Greek
+// text is NFC-normalized before tokenising so precomposed and decomposed
+// accents don't register as spurious changes.
+func DiffRevisions(a, b *Revision) RevisionDiff {
+	return RevisionDiff{
+		Greek:   diffText(nfc(a.CorrectedGreek), nfc(b.CorrectedGreek)),
+		English: diffText(a.CorrectedEnglish, b.CorrectedEnglish),
+	}
+}
+
+// ReviewDiff holds the word-level diffs between two Review rows' corrected
+// Greek and English fields. Unlike RevisionDiff (which compares two
+// review_revisions snapshots), this compares live Review values directly -
+// e.g. a pending edit against the row it would replace.
+type ReviewDiff struct {
+	Greek   []DiffOp
+	English []DiffOp
+}
+
+// DiffReview computes the word-level diff between two reviews' corrected
+// Greek and English text, the same way DiffRevisions does for revisions.
+func DiffReview(a, b *Review) ReviewDiff {
+	return ReviewDiff{
+		Greek:   diffText(nfc(a.CorrectedGreekText), nfc(b.CorrectedGreekText)),
+		English: diffText(a.CorrectedEnglishTranslation, b.CorrectedEnglishTranslation),
+	}
+}
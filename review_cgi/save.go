@@ -47,6 +47,8 @@ func main() {
 	reviewedEnglish := strings.TrimSpace(formData.Get("reviewed_english"))
 	notes := strings.TrimSpace(formData.Get("notes"))
 	action := formData.Get("action") // "stay" or "continue" (default)
+	workflowAction := formData.Get("workflow_action") // "approve", "request_changes", "comment", "draft"
+	commentText := strings.TrimSpace(formData.Get("comment_text"))
 	remoteUser := os.Getenv("REMOTE_USER")
 
 	// Validate required fields
@@ -74,6 +76,8 @@ func main() {
 
 	// Load configuration
 	config := GetConfig()
+	ctx, cancel := RequestContext(config)
+	defer cancel()
 
 	// Load lemma data
 	data, err := LoadLemmaData(config.DataFile)
@@ -83,15 +87,50 @@ func main() {
 	}
 
 	// Open database
-	db, err := OpenDatabase(config.DBPath)
+	db, err := OpenDatabase(ctx, config.DBPath)
 	if err != nil {
 		showErrorAndExit(fmt.Sprintf("Failed to open database: %v", err))
 		return
 	}
 	defer db.Close()
 
+	if err := ImportLemmas(ctx, db, config.DataFile); err != nil {
+		showErrorAndExit(fmt.Sprintf("Failed to import lemma data: %v", err))
+		return
+	}
+
+	// Approval-workflow actions (approve / request changes / comment) and
+	// unsubmitted drafts record a timeline event instead of finalizing the
+	// review row.
+	eventTypes := map[string]string{
+		"approve":         "approved",
+		"request_changes": "request_changes",
+		"comment":         "commented",
+	}
+	if eventType, ok := eventTypes[workflowAction]; ok {
+		if err := RecordReviewEvent(ctx, db, lemmaID, remoteUser, eventType, "overall", commentText); err != nil {
+			showErrorAndExit(fmt.Sprintf("Failed to record review event: %v", err))
+			return
+		}
+		redirectToLemma(lemmaID)
+		log.Printf("Review event recorded: lemma_id=%d, type=%s, user=%s", lemmaID, eventType, remoteUser)
+		return
+	}
+	if workflowAction == "draft" {
+		if correctedGreek != "" {
+			RecordReviewEvent(ctx, db, lemmaID, remoteUser, "pending", "greek", correctedGreek)
+		}
+		if correctedEnglish != "" {
+			RecordReviewEvent(ctx, db, lemmaID, remoteUser, "pending", "english", correctedEnglish)
+		}
+		fmt.Println("Content-Type: text/plain; charset=utf-8")
+		fmt.Println()
+		fmt.Println("draft saved")
+		return
+	}
+
 	// Get old review to track changes
-	oldReview, err := GetReview(db, lemmaID)
+	oldReview, err := GetReview(ctx, db, lemmaID)
 	if err != nil {
 		showErrorAndExit(fmt.Sprintf("Failed to get existing review: %v", err))
 		return
@@ -99,25 +138,41 @@ func main() {
 
 	// Create review record with new values, preserving "by" fields from old review
 	review := &Review{
-		LemmaID:                      lemmaID,
-		ReviewStatus:                 reviewStatus,
-		CorrectedGreekText:           correctedGreek,
-		CorrectedEnglishTranslation:  correctedEnglish,
-		ReviewedEnglishTranslation:   reviewedEnglish,
-		ReviewerUsername:             remoteUser,
-		Notes:                        notes,
-		GreekCorrectedBy:             oldReview.GreekCorrectedBy,
-		InitialTranslationBy:         oldReview.InitialTranslationBy,
-		ReviewedTranslationBy:        oldReview.ReviewedTranslationBy,
+		LemmaID:                     lemmaID,
+		ReviewStatus:                reviewStatus,
+		CorrectedGreekText:          correctedGreek,
+		CorrectedEnglishTranslation: correctedEnglish,
+		ReviewedEnglishTranslation:  reviewedEnglish,
+		ReviewerUsername:            remoteUser,
+		Notes:                       notes,
+		GreekCorrectedBy:            oldReview.GreekCorrectedBy,
+		InitialTranslationBy:        oldReview.InitialTranslationBy,
+		ReviewedTranslationBy:       oldReview.ReviewedTranslationBy,
 	}
+	if lemma, _ := FindLemmaByID(ctx, db, lemmaID); lemma != nil {
+		review.Letter = lemma.Letter
+	}
+
+	// Apply the SM-2 spaced-repetition schedule for this lemma based on how
+	// well the review went.
+	quality := reviewQuality(reviewStatus)
+	ef, intervalDays, repetitions, dueAt := ApplySM2(oldReview, quality)
+	review.EaseFactor = ef
+	review.IntervalDays = intervalDays
+	review.Repetitions = repetitions
+	review.DueAt = &dueAt
 
 	// Save to database
-	err = SaveReview(db, review, oldReview, remoteUser)
+	err = SaveReview(ctx, db, review, oldReview, remoteUser)
 	if err != nil {
 		showErrorAndExit(fmt.Sprintf("Failed to save review: %v", err))
 		return
 	}
 
+	// A real submission supersedes any outstanding draft this reviewer had.
+	ClearPendingDraft(ctx, db, lemmaID, remoteUser, "greek")
+	ClearPendingDraft(ctx, db, lemmaID, remoteUser, "english")
+
 	// Determine redirect target based on action
 	var redirectID int
 
@@ -126,7 +181,7 @@ func main() {
 		redirectID = lemmaID
 	} else {
 		// Default: continue to next lemma
-		currentLemma := FindLemmaByID(data, lemmaID)
+		currentLemma, _ := FindLemmaByID(ctx, db, lemmaID)
 		if currentLemma != nil {
 			nextLemma := GetNextLemma(data, currentLemma)
 			if nextLemma != nil {
@@ -141,21 +196,7 @@ func main() {
 	}
 
 	// Redirect to target entry
-	fmt.Println("Status: 303 See Other")
-	fmt.Printf("Location: /cgi-bin/review.cgi?id=%d\n", redirectID)
-	fmt.Println("Content-Type: text/html; charset=utf-8")
-	fmt.Println()
-	fmt.Printf(`<!DOCTYPE html>
-<html>
-<head>
-    <meta http-equiv="refresh" content="0;url=/cgi-bin/review.cgi?id=%d">
-    <title>Redirecting...</title>
-</head>
-<body>
-    <p>Review saved. Redirecting...</p>
-    <p><a href="/cgi-bin/review.cgi?id=%d">Click here if not redirected</a></p>
-</body>
-</html>`, redirectID, redirectID)
+	redirectToLemma(redirectID)
 
 	// Log successful save
 	log.Printf("Review saved: lemma_id=%d, status=%s, user=%s", lemmaID, reviewStatus, remoteUser)
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+// reviewQuality maps a review_status radio value to an SM-2 quality score
+// (0-5 scale, collapsed to the handful of grades this form can produce).
+func reviewQuality(reviewStatus string) int {
+	switch reviewStatus {
+	case "reviewed_ok":
+		return 5
+	case "reviewed_corrections":
+		return 3
+	default: // not_reviewed / skip
+		return 1
+	}
+}
+
+// ApplySM2 computes the next ease factor, interval, and repetition count for
+// a lemma using the SM-2 spaced-repetition algorithm, given the previous
+// scheduling state and the quality of the review just performed.
+func ApplySM2(prev *Review, quality int) (ef float64, intervalDays int, repetitions int, dueAt time.Time) {
+	ef = prev.EaseFactor
+	if ef <= 0 {
+		ef = 2.5
+	}
+	repetitions = prev.Repetitions
+
+	if quality < 3 {
+		repetitions = 0
+		intervalDays = 1
+	} else {
+		repetitions++
+		switch repetitions {
+		case 1:
+			intervalDays = 1
+		case 2:
+			intervalDays = 6
+		default:
+			intervalDays = int(math.Round(float64(prev.IntervalDays) * ef))
+		}
+	}
+
+	ef = ef + (0.1 - float64(5-quality)*(0.08+float64(5-quality)*0.02))
+	if ef < 1.3 {
+		ef = 1.3
+	}
+
+	dueAt = time.Now().AddDate(0, 0, intervalDays)
+	return ef, intervalDays, repetitions, dueAt
+}
+
+// GetNextScheduledLemma picks the lemma most in need of review according to
+// the SM-2 schedule: the one with the smallest due_at that is already due
+// (<=now), scoped to letter when letter is non-empty. If nothing is due yet,
+// it falls back to the lemma with the smallest due_at overall. Ties break by
+// sort order, matching GetNextUnreviewedInLetter's walk order.
+func GetNextScheduledLemma(ctx context.Context, db *sql.DB, data *LemmaData, letter string) (*Lemma, error) {
+	query := `
+		SELECT lemma_id, due_at
+		FROM reviews
+		WHERE due_at IS NOT NULL
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dueAtByID := make(map[int]time.Time)
+	for rows.Next() {
+		var lemmaID int
+		var dueAt time.Time
+		if err := rows.Scan(&lemmaID, &dueAt); err != nil {
+			continue
+		}
+		dueAtByID[lemmaID] = dueAt
+	}
+
+	now := time.Now()
+	var bestDue, bestAny *Lemma
+	var bestDueAt, bestAnyAt time.Time
+
+	for i := range data.Lemmas {
+		lemma := &data.Lemmas[i]
+		if letter != "" && lemma.Letter != letter {
+			continue
+		}
+		dueAt, ok := dueAtByID[lemma.ID]
+		if !ok {
+			continue
+		}
+
+		if bestAny == nil || dueAt.Before(bestAnyAt) {
+			bestAny = lemma
+			bestAnyAt = dueAt
+		}
+
+		if !dueAt.After(now) {
+			if bestDue == nil || dueAt.Before(bestDueAt) {
+				bestDue = lemma
+				bestDueAt = dueAt
+			}
+		}
+	}
+
+	if bestDue != nil {
+		return bestDue, nil
+	}
+	return bestAny, nil
+}
@@ -3,138 +3,234 @@
 // Returns review status for all lemmas in a given letter.
 // Used by the static reference site to show live OCR/translation status.
 //
-// Performance: Currently ~200-500ms per request (acceptable).
-// If performance becomes unacceptable, consider:
-//   - Adding an in-memory cache with TTL (e.g., 60 seconds)
-//   - Pre-computing status JSON on review save and serving from disk
-//   - Adding HTTP Cache-Control headers for browser/CDN caching
+// Responses are backed by a cache kept in the reviews database's
+// status_cache table (see statuscache.go) and
+// served with a strong ETag/Last-Modified pair so that repeated polling
+// from the static site can be answered with 304s instead of re-scanning
+// the reviews table every time.
 
 package main
 
 import (
+	"context"
+	"crypto/sha1"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/cgi"
+	"strconv"
 	"strings"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // LemmaStatus represents the review status for a single lemma
 type LemmaStatus struct {
-	OCRChecked           bool   `json:"ocr_checked"`
-	InitialTranslation   bool   `json:"initial_translation"`
-	TranslationConfirmed bool   `json:"translation_confirmed"`
-	OCRCheckedBy         string `json:"ocr_checked_by,omitempty"`
-	InitialTranslationBy string `json:"initial_translation_by,omitempty"`
+	OCRChecked             bool   `json:"ocr_checked"`
+	InitialTranslation     bool   `json:"initial_translation"`
+	TranslationConfirmed   bool   `json:"translation_confirmed"`
+	OCRCheckedBy           string `json:"ocr_checked_by,omitempty"`
+	InitialTranslationBy   string `json:"initial_translation_by,omitempty"`
 	TranslationConfirmedBy string `json:"translation_confirmed_by,omitempty"`
+	RevisionCount          int    `json:"revision_count"`
+	LastReviewer           string `json:"last_reviewer,omitempty"`
+}
+
+// HistoryResponse is the JSON response from the ?history=<id> endpoint.
+type HistoryResponse struct {
+	LemmaID   int        `json:"lemma_id"`
+	Revisions []Revision `json:"revisions"`
+	Error     string     `json:"error,omitempty"`
 }
 
 // StatusResponse is the JSON response from the status endpoint
 type StatusResponse struct {
-	Letter      string                 `json:"letter"`
-	Statuses    map[int]LemmaStatus    `json:"statuses"`
-	LemmaCount  int                    `json:"lemma_count"`
-	ReviewCount int                    `json:"review_count"`
-	TimingMs    float64                `json:"timing_ms"`
-	Error       string                 `json:"error,omitempty"`
+	Letter      string              `json:"letter"`
+	Statuses    map[int]LemmaStatus `json:"statuses"`
+	LemmaCount  int                 `json:"lemma_count"`
+	ReviewCount int                 `json:"review_count"`
+	Error       string              `json:"error,omitempty"`
 }
 
 func main() {
-	cgi.Serve(http.HandlerFunc(handleStatus))
+	config := GetConfig()
+	cgi.Serve(WithAccessLog(config, http.HandlerFunc(handleStatus)))
 }
 
-func handleStatus(w http.ResponseWriter, r *http.Request) {
-	startTime := time.Now()
+// SearchResult is one match returned by the ?search= endpoint.
+type SearchResult struct {
+	ID                 int    `json:"id"`
+	Lemma              string `json:"lemma"`
+	Letter             string `json:"letter"`
+	GreekText          string `json:"greek_text"`
+	EnglishTranslation string `json:"english_translation"`
+}
 
-	w.Header().Set("Content-Type", "application/json")
+// SearchResponse is the JSON response from the ?search= endpoint
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Error   string         `json:"error,omitempty"`
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	if query := r.URL.Query().Get("search"); query != "" {
+		handleSearch(w, r, query)
+		return
+	}
+
+	if historyID := r.URL.Query().Get("history"); historyID != "" {
+		handleHistory(w, r, historyID)
+		return
+	}
+
 	// Get letter parameter
 	letter := r.URL.Query().Get("letter")
 	if letter == "" {
-		writeError(w, "missing 'letter' parameter", startTime)
+		w.Header().Set("Content-Type", "application/json")
+		writeError(w, "missing 'letter' parameter")
 		return
 	}
 	letter = strings.ToLower(letter)
 
-	// Load lemma data to get letter -> lemma_id mapping
 	config := GetConfig()
-	data, err := LoadLemmaData(config.DataFile)
+	ctx, cancel := context.WithTimeout(r.Context(), config.QueryTimeout)
+	defer cancel()
+
+	db, err := OpenDatabase(ctx, config.DBPath)
 	if err != nil {
-		writeError(w, fmt.Sprintf("failed to load lemma data: %v", err), startTime)
+		w.Header().Set("Content-Type", "application/json")
+		if ctx.Err() != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(StatusResponse{
+				Error: fmt.Sprintf("timed out during open-db: %v", err),
+			})
+			return
+		}
+		writeError(w, fmt.Sprintf("failed to open database: %v", err))
 		return
 	}
+	defer db.Close()
 
-	// Build set of lemma IDs for the requested letter
-	lemmaIDs := make(map[int]bool)
-	for _, lemma := range data.Lemmas {
-		if strings.ToLower(lemma.Letter) == letter {
-			lemmaIDs[lemma.ID] = true
+	var entry statusCacheEntry
+	if cached, ok := getCachedStatus(ctx, db, letter); ok {
+		entry = cached
+	} else {
+		fresh, err := computeStatus(ctx, db, config, letter)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			if stageErr, ok := err.(*statusStageError); ok && ctx.Err() != nil {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				json.NewEncoder(w).Encode(StatusResponse{
+					Error: fmt.Sprintf("timed out during %s: %v", stageErr.Stage, stageErr.Err),
+				})
+				return
+			}
+			writeError(w, err.Error())
+			return
 		}
+		entry = fresh
+		setCachedStatus(ctx, db, letter, entry, config.StatusCacheTTL)
 	}
 
-	if len(lemmaIDs) == 0 {
-		writeError(w, fmt.Sprintf("no lemmas found for letter '%s'", letter), startTime)
+	SetAccessLogField(r, "lemma_count", strconv.Itoa(entry.LemmaCount))
+	SetAccessLogField(r, "review_count", strconv.Itoa(entry.ReviewCount))
+
+	w.Header().Set("ETag", entry.ETag)
+	w.Header().Set("Last-Modified", entry.LastMod.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "public, max-age=60")
+
+	if notModified(r, entry) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	// Open database
-	db, err := sql.Open("sqlite3", config.DBPath)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(entry.Body)
+}
+
+// notModified reports whether the client's cache validators (If-None-Match
+// takes priority over If-Modified-Since, per RFC 7232) show it already has
+// the current representation.
+func notModified(r *http.Request, entry statusCacheEntry) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return inm == entry.ETag
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !entry.LastMod.After(t)
+		}
+	}
+	return false
+}
+
+// statusStageError tags an error with which stage of computeStatus produced
+// it (load-json / query-reviews; handleStatus reports an open-db timeout
+// itself, since the database is opened before computeStatus runs), so a
+// timed-out request can tell
+// operators where the time went instead of just "context deadline exceeded".
+type statusStageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *statusStageError) Error() string { return fmt.Sprintf("%s: %v", e.Stage, e.Err) }
+func (e *statusStageError) Unwrap() error { return e.Err }
+
+// computeStatus runs the full reviews scan for letter and builds the cache
+// entry (JSON body, ETag, Last-Modified) that handleStatus serves and
+// statuscache.go caches. db is already open (handleStatus needs it before
+// computeStatus runs, to check the cache). ctx bounds the whole call; if
+// it's done by the time a stage fails, the returned error is a
+// *statusStageError naming that stage.
+func computeStatus(ctx context.Context, db *sql.DB, config Config, letter string) (statusCacheEntry, error) {
+	if err := ImportLemmas(ctx, db, config.DataFile); err != nil {
+		return statusCacheEntry{}, &statusStageError{"load-json", err}
+	}
+
+	// Build set of lemma IDs for the requested letter straight from the
+	// lemmas table (lemmas_letter_idx-backed) instead of scanning the JSON
+	// export in memory.
+	ids, err := lemmaIDsForLetter(ctx, db, letter)
 	if err != nil {
-		writeError(w, fmt.Sprintf("failed to open database: %v", err), startTime)
-		return
+		return statusCacheEntry{}, &statusStageError{"query-reviews", fmt.Errorf("querying lemmas for letter '%s': %w", letter, err)}
+	}
+	if len(ids) == 0 {
+		return statusCacheEntry{}, fmt.Errorf("no lemmas found for letter '%s'", letter)
 	}
-	defer db.Close()
 
-	// Query all reviews
-	query := `
-		SELECT lemma_id,
-		       COALESCE(corrected_greek_text, ''),
-		       COALESCE(corrected_english_translation, ''),
-		       COALESCE(reviewed_english_translation, ''),
-		       COALESCE(greek_corrected_by, ''),
-		       COALESCE(initial_translation_by, ''),
-		       COALESCE(reviewed_translation_by, '')
-		FROM reviews
-	`
-
-	rows, err := db.Query(query)
+	reviewsByLemma, err := GetReviewsForLemmas(ctx, db, ids)
 	if err != nil {
-		writeError(w, fmt.Sprintf("failed to query reviews: %v", err), startTime)
-		return
+		return statusCacheEntry{}, &statusStageError{"query-reviews", err}
+	}
+
+	revisionSummaries, err := GetRevisionSummariesForLemmas(ctx, db, ids)
+	if err != nil {
+		return statusCacheEntry{}, &statusStageError{"query-reviews", err}
 	}
-	defer rows.Close()
 
 	// Build status map for matching lemmas
 	statuses := make(map[int]LemmaStatus)
 	reviewCount := 0
+	var maxReviewedAt time.Time
 
-	for rows.Next() {
-		var lemmaID int
-		var greekText, englishTrans, reviewedTrans string
-		var greekBy, initialBy, reviewedBy string
-
-		if err := rows.Scan(&lemmaID, &greekText, &englishTrans, &reviewedTrans, &greekBy, &initialBy, &reviewedBy); err != nil {
-			continue
-		}
-
-		// Only include if this lemma is in the requested letter
-		if !lemmaIDs[lemmaID] {
-			continue
-		}
-
+	for lemmaID, review := range reviewsByLemma {
 		reviewCount++
+		if review.ReviewedAt != nil && review.ReviewedAt.After(maxReviewedAt) {
+			maxReviewedAt = *review.ReviewedAt
+		}
+		summary := revisionSummaries[lemmaID]
 		statuses[lemmaID] = LemmaStatus{
-			OCRChecked:             greekText != "",
-			InitialTranslation:     englishTrans != "",
-			TranslationConfirmed:   reviewedTrans != "",
-			OCRCheckedBy:           greekBy,
-			InitialTranslationBy:   initialBy,
-			TranslationConfirmedBy: reviewedBy,
+			OCRChecked:             review.CorrectedGreekText != "",
+			InitialTranslation:     review.CorrectedEnglishTranslation != "",
+			TranslationConfirmed:   review.ReviewedEnglishTranslation != "",
+			OCRCheckedBy:           review.GreekCorrectedBy,
+			InitialTranslationBy:   review.InitialTranslationBy,
+			TranslationConfirmedBy: review.ReviewedTranslationBy,
+			RevisionCount:          summary.Count,
+			LastReviewer:           summary.LastReviewer,
 		}
 	}
 
@@ -142,19 +238,143 @@ func handleStatus(w http.ResponseWriter, r *http.Request) {
 	response := StatusResponse{
 		Letter:      letter,
 		Statuses:    statuses,
-		LemmaCount:  len(lemmaIDs),
+		LemmaCount:  len(ids),
 		ReviewCount: reviewCount,
-		TimingMs:    float64(time.Since(startTime).Microseconds()) / 1000.0,
 	}
 
-	json.NewEncoder(w).Encode(response)
+	body, err := json.Marshal(response)
+	if err != nil {
+		return statusCacheEntry{}, fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha1.Sum([]byte(fmt.Sprintf("%s|%d|%d", letter, maxReviewedAt.UnixNano(), reviewCount))))
+
+	return statusCacheEntry{
+		Body:        body,
+		ETag:        etag,
+		LastMod:     maxReviewedAt,
+		LemmaCount:  len(ids),
+		ReviewCount: reviewCount,
+	}, nil
 }
 
-func writeError(w http.ResponseWriter, message string, startTime time.Time) {
-	response := StatusResponse{
-		Error:    message,
-		TimingMs: float64(time.Since(startTime).Microseconds()) / 1000.0,
+// lemmaIDsForLetter returns the IDs of every lemma in the lemmas table whose
+// letter matches (case-insensitively).
+func lemmaIDsForLetter(ctx context.Context, db *sql.DB, letter string) ([]int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id FROM lemmas WHERE LOWER(letter) = ?`, letter)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// handleSearch answers ?search=<query> against the lemmas_fts FTS5 index
+// over greek_text/english_translation. Unlike the per-letter status
+// responses, search results aren't cached (queries are unbounded, so there's
+// no small cache key space worth holding onto).
+func handleSearch(w http.ResponseWriter, r *http.Request, query string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	config := GetConfig()
+	ctx, cancel := context.WithTimeout(r.Context(), config.QueryTimeout)
+	defer cancel()
+
+	db, err := OpenDatabase(ctx, config.DBPath)
+	if err != nil {
+		writeSearchError(w, fmt.Sprintf("failed to open database: %v", err))
+		return
+	}
+	defer db.Close()
+
+	if err := ImportLemmas(ctx, db, config.DataFile); err != nil {
+		writeSearchError(w, fmt.Sprintf("failed to import lemma data: %v", err))
+		return
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT lemmas.id, lemmas.lemma, lemmas.letter, lemmas.greek_text, lemmas.english_translation
+		FROM lemmas_fts
+		JOIN lemmas ON lemmas.id = lemmas_fts.rowid
+		WHERE lemmas_fts MATCH ?
+		ORDER BY rank
+		LIMIT 50
+	`, query)
+	if err != nil {
+		writeSearchError(w, fmt.Sprintf("search failed: %v", err))
+		return
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var result SearchResult
+		if err := rows.Scan(&result.ID, &result.Lemma, &result.Letter, &result.GreekText, &result.EnglishTranslation); err != nil {
+			writeSearchError(w, fmt.Sprintf("failed to read search results: %v", err))
+			return
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		writeSearchError(w, fmt.Sprintf("failed to read search results: %v", err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(SearchResponse{Query: query, Results: results})
+}
+
+func writeSearchError(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(SearchResponse{Error: message})
+}
+
+// handleHistory answers ?history=<id> with a lemma's full revision chain, so
+// the static site can render "what changed since revision N" without going
+// through history.cgi's HTML page.
+func handleHistory(w http.ResponseWriter, r *http.Request, idParam string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	lemmaID, err := strconv.Atoi(idParam)
+	if err != nil {
+		writeHistoryError(w, "invalid 'history' id")
+		return
+	}
+
+	config := GetConfig()
+	ctx, cancel := context.WithTimeout(r.Context(), config.QueryTimeout)
+	defer cancel()
+
+	db, err := OpenDatabase(ctx, config.DBPath)
+	if err != nil {
+		writeHistoryError(w, fmt.Sprintf("failed to open database: %v", err))
+		return
+	}
+	defer db.Close()
+
+	revisions, err := GetReviewHistory(ctx, db, lemmaID)
+	if err != nil {
+		writeHistoryError(w, fmt.Sprintf("failed to load history: %v", err))
+		return
+	}
+
+	json.NewEncoder(w).Encode(HistoryResponse{LemmaID: lemmaID, Revisions: revisions})
+}
+
+func writeHistoryError(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(HistoryResponse{Error: message})
+}
+
+func writeError(w http.ResponseWriter, message string) {
 	w.WriteHeader(http.StatusBadRequest)
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(StatusResponse{Error: message})
 }
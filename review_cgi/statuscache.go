@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// statusCacheEntry is a cached, already-marshalled status.cgi response for
+// one letter, along with the ETag/Last-Modified it was served with and the
+// counts handleStatus annotates the access log with on every hit, not just
+// on the request that computed them.
+type statusCacheEntry struct {
+	Body        []byte
+	ETag        string
+	LastMod     time.Time
+	ExpiresAt   time.Time
+	LemmaCount  int
+	ReviewCount int
+}
+
+// ensureStatusCacheTable creates the status_cache table if it doesn't
+// already exist. status.cgi runs under net/http/cgi, which serves exactly
+// one request per process, so a package-level map can never be hit by a
+// later request - it's always empty at the start of a fresh process. The
+// reviews database is the one thing that does outlive a single request, so
+// that's where the cache has to live.
+func ensureStatusCacheTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS status_cache (
+			letter TEXT PRIMARY KEY,
+			body BLOB,
+			etag TEXT,
+			last_mod TIMESTAMP,
+			expires_at TIMESTAMP,
+			lemma_count INTEGER,
+			review_count INTEGER
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create status_cache table: %w", err)
+	}
+	return nil
+}
+
+// getCachedStatus returns the cached entry for letter if it exists and
+// hasn't expired.
+func getCachedStatus(ctx context.Context, db *sql.DB, letter string) (statusCacheEntry, bool) {
+	if err := ensureStatusCacheTable(ctx, db); err != nil {
+		return statusCacheEntry{}, false
+	}
+
+	var entry statusCacheEntry
+	err := db.QueryRowContext(ctx, `
+		SELECT body, etag, last_mod, expires_at, lemma_count, review_count
+		FROM status_cache
+		WHERE letter = ?
+	`, letter).Scan(&entry.Body, &entry.ETag, &entry.LastMod, &entry.ExpiresAt, &entry.LemmaCount, &entry.ReviewCount)
+	if err != nil {
+		return statusCacheEntry{}, false
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		return statusCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// setCachedStatus stores a freshly computed status response, valid for ttl.
+func setCachedStatus(ctx context.Context, db *sql.DB, letter string, entry statusCacheEntry, ttl time.Duration) {
+	if err := ensureStatusCacheTable(ctx, db); err != nil {
+		return
+	}
+	entry.ExpiresAt = time.Now().Add(ttl)
+
+	// Best-effort: a failed cache write shouldn't take the request down,
+	// the caller already has the freshly computed entry to serve.
+	db.ExecContext(ctx, `
+		INSERT INTO status_cache (letter, body, etag, last_mod, expires_at, lemma_count, review_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(letter) DO UPDATE SET
+			body = excluded.body,
+			etag = excluded.etag,
+			last_mod = excluded.last_mod,
+			expires_at = excluded.expires_at,
+			lemma_count = excluded.lemma_count,
+			review_count = excluded.review_count
+	`, letter, entry.Body, entry.ETag, entry.LastMod, entry.ExpiresAt, entry.LemmaCount, entry.ReviewCount)
+}
+
+// InvalidateStatusCache busts the cached status response for a letter, so a
+// review write is reflected on the next request instead of waiting out the
+// TTL. SaveReview calls this when it knows which letter a saved lemma
+// belongs to.
+func InvalidateStatusCache(ctx context.Context, db *sql.DB, letter string) {
+	if letter == "" {
+		return
+	}
+	letter = strings.ToLower(letter)
+
+	if err := ensureStatusCacheTable(ctx, db); err != nil {
+		return
+	}
+	db.ExecContext(ctx, `DELETE FROM status_cache WHERE letter = ?`, letter)
+}
@@ -6,6 +6,7 @@ const reviewTemplate = `<!DOCTYPE html>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>Review: {{.Lemma.Lemma}} - Stephanos Review System</title>
+    <script src="https://cdn.jsdelivr.net/npm/openseadragon@4/build/openseadragon/openseadragon.min.js"></script>
     <style>
         * {
             margin: 0;
@@ -145,6 +146,14 @@ const reviewTemplate = `<!DOCTYPE html>
             border-radius: 4px;
             box-shadow: 0 2px 4px rgba(0,0,0,0.1);
         }
+        .osd-viewer {
+            width: 100%;
+            min-width: 320px;
+            height: 480px;
+            border: 2px solid #ecf0f1;
+            border-radius: 4px;
+            background: #000;
+        }
         .review-form {
             margin-top: 20px;
         }
@@ -217,9 +226,17 @@ const reviewTemplate = `<!DOCTYPE html>
         .btn-skip:hover {
             background: #7f8c8d;
         }
+        mark.ann-mark {
+            background: #fff3b0;
+            cursor: pointer;
+        }
+        .hide-resolved mark.ann-resolved {
+            background: none;
+            cursor: default;
+        }
     </style>
 </head>
-<body>
+<body class="{{if not .ShowResolved}}hide-resolved{{end}}">
     <div class="header">
         <h1>Stephanos Review System</h1>
         <div>Reviewed {{.ReviewedCount}} of {{.TotalCount}} entries ({{.PercentComplete}}%)</div>
@@ -254,6 +271,14 @@ const reviewTemplate = `<!DOCTYPE html>
                     No More Unreviewed in {{.LetterName}}
                 </button>
                 {{end}}
+
+                <button class="next-unreviewed" onclick="window.location.href='?action=next_due&id={{.Lemma.ID}}'">
+                    Next Due for Review →
+                </button>
+
+                <button class="next-unreviewed" onclick="window.location.href='?action=next_with_open_annotations&id={{.Lemma.ID}}'">
+                    Next With Open Annotations ({{.OpenAnnotationCount}} here) →
+                </button>
             </div>
             <div class="metadata">
                 Entry {{.CurrentPosition}} of {{.TotalCount}}
@@ -265,6 +290,9 @@ const reviewTemplate = `<!DOCTYPE html>
                 <div>
                     <span class="lemma-title">{{.Lemma.Lemma}}</span>
                     <span class="version-badge">{{.Lemma.Version}}</span>
+                    {{if .DueSoon}}
+                    <span class="version-badge" style="background: #e67e22;">Due soon</span>
+                    {{end}}
                     {{if .Lemma.Type}}
                     <div style="margin-top: 8px;">
                         <span style="background: #3498db; color: white; padding: 4px 10px; border-radius: 4px; font-size: 0.85em;">
@@ -278,24 +306,31 @@ const reviewTemplate = `<!DOCTYPE html>
                     {{.Lemma.VolumeLabel}}<br>
                     {{if .Lemma.MeinekeID}}Meineke: {{.Lemma.MeinekeID}}<br>{{end}}
                     {{if .Lemma.BillerbeckID}}Billerbeck: {{.Lemma.BillerbeckID}}<br>{{end}}
-                    {{.Lemma.WordCount}} words
+                    {{.Lemma.WordCount}} words<br>
+                    {{if .LatestRevision}}
+                    Last edited by {{.LatestRevision.Reviewer}} on {{.LatestRevision.CreatedAt.Format "2006-01-02 15:04"}}<br>
+                    {{end}}
+                    <a href="/cgi-bin/history.cgi?id={{.Lemma.ID}}">View history</a>
                 </div>
             </div>
 
-            <div class="section-title">Original Greek Text</div>
-            <div class="original-text">{{.Lemma.GreekText}}</div>
+            <div class="section-title">
+                Original Greek Text
+                {{if .OpenAnnotationCount}}<span class="version-badge" style="background: #e67e22;">{{.OpenAnnotationCount}} open annotation(s)</span>{{end}}
+            </div>
+            <div class="original-text" data-field="greek_text">{{annotateText .Lemma.GreekText "greek_text" .Annotations}}</div>
 
             <div class="section-title">Original English Translation</div>
-            <div class="original-text">{{.Lemma.EnglishTranslation}}</div>
+            <div class="original-text" data-field="english_translation">{{annotateText .Lemma.EnglishTranslation "english_translation" .Annotations}}</div>
 
             {{if .Lemma.ImageFilenames}}
             <div class="section-title">Source Page Images</div>
             <div class="images">
-                {{range $filename := .Lemma.ImageFilenames}}
+                {{range $i, $filename := .Lemma.ImageFilenames}}
                 <div>
-                    <img src="/protected/{{$filename}}" alt="{{$filename}}">
+                    <div id="osd-{{$i}}" class="osd-viewer" data-filename="{{$filename}}" data-lemma-id="{{$.Lemma.ID}}"></div>
                     <div style="text-align: center; font-size: 0.85em; color: #7f8c8d; margin-top: 5px;">
-                        {{$filename}}
+                        {{$filename}} &mdash; <a href="/protected/{{$filename}}" target="_blank">open original</a>
                     </div>
                 </div>
                 {{end}}
@@ -352,8 +387,278 @@ const reviewTemplate = `<!DOCTYPE html>
                     </button>
                 </div>
             </form>
+
+            <div class="button-group">
+                <form method="POST" action="/cgi-bin/save.cgi" style="display:inline;">
+                    <input type="hidden" name="lemma_id" value="{{.Lemma.ID}}">
+                    <input type="hidden" name="workflow_action" value="approve">
+                    <button type="submit" class="btn-save">Approve</button>
+                </form>
+                <form method="POST" action="/cgi-bin/save.cgi" style="display:inline;">
+                    <input type="hidden" name="lemma_id" value="{{.Lemma.ID}}">
+                    <input type="hidden" name="workflow_action" value="request_changes">
+                    <input type="text" name="comment_text" placeholder="What needs to change?">
+                    <button type="submit" class="btn-skip">Request changes</button>
+                </form>
+                <form method="POST" action="/cgi-bin/save.cgi" style="display:inline;">
+                    <input type="hidden" name="lemma_id" value="{{.Lemma.ID}}">
+                    <input type="hidden" name="workflow_action" value="comment">
+                    <input type="text" name="comment_text" placeholder="Add a comment">
+                    <button type="submit" class="btn-skip">Comment</button>
+                </form>
+            </div>
+        </div>
+
+        <div class="card">
+            <div class="section-title">
+                Review Timeline
+                <span class="version-badge" style="background: {{if eq .ApprovalStatus "approved"}}#27ae60{{else}}#e67e22{{end}};">
+                    {{.ApprovalStatus}}
+                </span>
+            </div>
+            {{if .Events}}
+            <ul style="list-style: none;">
+                {{range .Events}}
+                <li style="padding: 8px 0; border-bottom: 1px solid #ecf0f1;">
+                    <strong>{{.Reviewer}}</strong>
+                    {{if eq .EventType "pending"}}
+                    <span class="version-badge" style="background: #bdc3c7;">draft</span>
+                    {{else}}
+                    {{.EventType}}
+                    {{end}}
+                    on {{.Field}}
+                    {{if .Content}}&mdash; {{.Content}}{{end}}
+                    <span class="metadata">{{.CreatedAt.Format "2006-01-02 15:04"}}</span>
+                </li>
+                {{end}}
+            </ul>
+            {{else}}
+            <p class="metadata">No review activity yet.</p>
+            {{end}}
+        </div>
+
+        <div class="card">
+            <div class="section-title">
+                Annotations
+                {{if .ShowResolved}}
+                <a href="?id={{.Lemma.ID}}">Hide resolved</a>
+                {{else}}
+                <a href="?id={{.Lemma.ID}}&show_resolved=1">Show resolved</a>
+                {{end}}
+            </div>
+            {{if .Annotations}}
+            <ul style="list-style: none;">
+                {{range .Annotations}}
+                <li id="ann-{{.ID}}" style="padding: 8px 0; border-bottom: 1px solid #ecf0f1;">
+                    <strong>{{.Author}}</strong> on {{.Field}}: &ldquo;{{.QuotedText}}&rdquo;
+                    {{if .Resolved}}<span class="version-badge" style="background: #95a5a6;">resolved</span>{{end}}
+                    <br>{{.Body}}
+                    {{if not .Resolved}}
+                    <form method="POST" action="/cgi-bin/annotate.cgi" style="display:inline;">
+                        <input type="hidden" name="lemma_id" value="{{$.Lemma.ID}}">
+                        <input type="hidden" name="action" value="resolve">
+                        <input type="hidden" name="annotation_id" value="{{.ID}}">
+                        <button type="submit" class="btn-skip">Resolve</button>
+                    </form>
+                    {{end}}
+                </li>
+                {{end}}
+            </ul>
+            {{else}}
+            <p class="metadata">No annotations yet. Select text above to comment on it.</p>
+            {{end}}
         </div>
     </div>
+
+    <script>
+    function codepointLength(s) { return Array.from(s).length; }
+
+    function computeOffsets(container, range) {
+        var pre = document.createRange();
+        pre.selectNodeContents(container);
+        pre.setEnd(range.startContainer, range.startOffset);
+        var start = codepointLength(pre.toString());
+        var selected = range.toString();
+        return {start: start, end: start + codepointLength(selected), text: selected};
+    }
+
+    document.querySelectorAll('.original-text[data-field]').forEach(function(el) {
+        el.addEventListener('mouseup', function() {
+            var sel = window.getSelection();
+            if (!sel || sel.isCollapsed || sel.toString().trim() === '') return;
+            var offsets = computeOffsets(el, sel.getRangeAt(0));
+            var body = prompt('Comment on selection: "' + offsets.text + '"');
+            if (!body) return;
+
+            var form = document.createElement('form');
+            form.method = 'POST';
+            form.action = '/cgi-bin/annotate.cgi';
+            var fields = {
+                lemma_id: '{{.Lemma.ID}}',
+                field: el.dataset.field,
+                start_offset: offsets.start,
+                end_offset: offsets.end,
+                quoted_text: offsets.text,
+                body: body
+            };
+            for (var key in fields) {
+                var input = document.createElement('input');
+                input.type = 'hidden';
+                input.name = key;
+                input.value = fields[key];
+                form.appendChild(input);
+            }
+            document.body.appendChild(form);
+            form.submit();
+        });
+    });
+
+    document.querySelectorAll('mark[data-ann-id]').forEach(function(mark) {
+        mark.addEventListener('click', function() {
+            window.location.hash = 'ann-' + mark.dataset.annId;
+        });
+    });
+
+    // Autosave a pending draft of in-progress corrections (save.cgi records
+    // it as a review_event visible only to this reviewer, in their own
+    // timeline) without the author having to hit Save first. Other reviewers
+    // do not see the draft.
+    (function() {
+        var greekEl = document.getElementById('corrected_greek');
+        var englishEl = document.getElementById('corrected_english');
+        var timer;
+        function saveDraft() {
+            var body = new URLSearchParams({
+                lemma_id: '{{.Lemma.ID}}',
+                workflow_action: 'draft',
+                corrected_greek: greekEl ? greekEl.value : '',
+                corrected_english: englishEl ? englishEl.value : ''
+            });
+            fetch('/cgi-bin/save.cgi', {method: 'POST', body: body});
+        }
+        [greekEl, englishEl].forEach(function(el) {
+            if (!el) return;
+            el.addEventListener('input', function() {
+                clearTimeout(timer);
+                timer = setTimeout(saveDraft, 1000);
+            });
+        });
+    })();
+
+    // Deep-zoom viewers for the source page scans, backed by iiif.cgi.
+    // Dragging a rectangle on a scan attaches a note to the lemma through
+    // the same annotations subsystem used for text selections, with
+    // field "image:<filename>" and the region packed into quoted_text.
+    document.querySelectorAll('.osd-viewer').forEach(function(el) {
+        var filename = el.dataset.filename;
+        var viewer = OpenSeadragon({
+            element: el,
+            tileSources: '/cgi-bin/iiif.cgi/' + encodeURIComponent(filename) + '/info.json',
+            showNavigator: true
+        });
+
+        var dragStart = null;
+        viewer.addHandler('canvas-press', function(event) {
+            dragStart = viewer.viewport.pointFromPixel(event.position);
+        });
+        viewer.addHandler('canvas-release', function(event) {
+            if (!dragStart) return;
+            var dragEnd = viewer.viewport.pointFromPixel(event.position);
+            var topLeft = viewer.viewport.viewportToImageCoordinates(
+                new OpenSeadragon.Point(Math.min(dragStart.x, dragEnd.x), Math.min(dragStart.y, dragEnd.y)));
+            var bottomRight = viewer.viewport.viewportToImageCoordinates(
+                new OpenSeadragon.Point(Math.max(dragStart.x, dragEnd.x), Math.max(dragStart.y, dragEnd.y)));
+            dragStart = null;
+
+            var w = bottomRight.x - topLeft.x, h = bottomRight.y - topLeft.y;
+            if (w < 5 || h < 5) return; // treat as a click/pan, not a selection
+
+            var body = prompt('Note for this region of the scan:');
+            if (!body) return;
+
+            var form = document.createElement('form');
+            form.method = 'POST';
+            form.action = '/cgi-bin/annotate.cgi';
+            var fields = {
+                lemma_id: '{{.Lemma.ID}}',
+                field: 'image:' + filename,
+                start_offset: 0,
+                end_offset: 0,
+                quoted_text: Math.round(topLeft.x) + ',' + Math.round(topLeft.y) + ',' + Math.round(w) + ',' + Math.round(h),
+                body: body
+            };
+            for (var key in fields) {
+                var input = document.createElement('input');
+                input.type = 'hidden';
+                input.name = key;
+                input.value = fields[key];
+                form.appendChild(input);
+            }
+            document.body.appendChild(form);
+            form.submit();
+        });
+    });
+    </script>
+</body>
+</html>
+`
+
+const historyTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>Revision History - Lemma {{.LemmaID}}</title>
+    <style>
+        body { font-family: sans-serif; max-width: 900px; margin: 30px auto; color: #333; }
+        table { border-collapse: collapse; width: 100%; }
+        th, td { text-align: left; padding: 6px 10px; border-bottom: 1px solid #ecf0f1; }
+        .diff ins { background: #d4f7d4; text-decoration: none; }
+        .diff del { background: #f7d4d4; }
+        .diff { font-family: 'Times New Roman', serif; font-size: 1.05em; line-height: 1.8; }
+    </style>
+</head>
+<body>
+    <p><a href="/cgi-bin/review.cgi?id={{.LemmaID}}">← Back to review</a></p>
+    <h1>Revision History</h1>
+
+    <form method="GET" action="/cgi-bin/history.cgi">
+        <input type="hidden" name="id" value="{{.LemmaID}}">
+        <table>
+            <tr><th></th><th></th><th>When</th><th>Reviewer</th><th>Status</th><th>Revert</th></tr>
+            {{range .Revisions}}
+            <tr>
+                <td><input type="radio" name="from" value="{{.ID}}" {{if eq $.FromID .ID}}checked{{end}}></td>
+                <td><input type="radio" name="to" value="{{.ID}}" {{if eq $.ToID .ID}}checked{{end}}></td>
+                <td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td>
+                <td>{{.Reviewer}}</td>
+                <td>{{.ReviewStatus}}</td>
+                <td>
+                    <button form="revert-{{.ID}}" type="submit">Revert</button>
+                </td>
+            </tr>
+            {{end}}
+        </table>
+        <button type="submit">Compare selected revisions</button>
+    </form>
+
+    {{range .Revisions}}
+    <form id="revert-{{.ID}}" method="POST" action="/cgi-bin/history.cgi" style="display:none;">
+        <input type="hidden" name="lemma_id" value="{{$.LemmaID}}">
+        <input type="hidden" name="revision_id" value="{{.ID}}">
+    </form>
+    {{end}}
+
+    {{if .Diff}}
+    <h2>Changes from revision {{.FromID}} to {{.ToID}}</h2>
+    <h3>Greek</h3>
+    <p class="diff">
+        {{range .Diff.Greek}}{{if eq .Type "equal"}}{{.Text}} {{else if eq .Type "insert"}}<ins>{{.Text}}</ins> {{else}}<del>{{.Text}}</del> {{end}}{{end}}
+    </p>
+    <h3>English</h3>
+    <p class="diff">
+        {{range .Diff.English}}{{if eq .Type "equal"}}{{.Text}} {{else if eq .Type "insert"}}<ins>{{.Text}}</ins> {{else}}<del>{{.Text}}</del> {{end}}{{end}}
+    </p>
+    {{end}}
 </body>
 </html>
 `